@@ -1,43 +1,55 @@
 package gg
 
-import "github.com/mastercactapus/gg/gcode"
+import (
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/svg"
+)
 
 var (
 	FeedRateX = 600.0
 	FeedRateY = 600.0
 	FeedRateZ = 300.0
 
-	setUnits = false
-	absMode  = true
 	firstAbs = true
-	lastNum  = 0.0
-	lastFeed = 0.0
-
-	zPos = 0.0
+	state    = gcode.NewInterpreter()
 )
 
 var lines []gcode.Line
 
+// CurrentZ returns the Z position after the last generated line.
 func CurrentZ() float64 {
-	return zPos
+	return state.State().Position[2]
 }
 
 func feedRate(l gcode.Line) float64 {
 	rate := 0.0
+	limit := func(r float64) {
+		if rate == 0.0 || rate > r {
+			rate = r
+		}
+	}
 	for _, w := range l {
 		switch w.Type {
 		case 'X':
-			if rate == 0.0 || rate > FeedRateX {
-				rate = FeedRateX
-			}
+			limit(FeedRateX)
 		case 'Y':
-			if rate == 0.0 || rate > FeedRateY {
-				rate = FeedRateY
-			}
+			limit(FeedRateY)
 		case 'Z':
-			if rate == 0.0 || rate > FeedRateZ {
-				rate = FeedRateZ
-			}
+			limit(FeedRateZ)
+		}
+	}
+	if l[0].Value == 2 || l[0].Value == 3 {
+		// arcs move both plane axes, even if only one (or neither) have words
+		switch state.State().Plane {
+		case gcode.PlaneXY:
+			limit(FeedRateX)
+			limit(FeedRateY)
+		case gcode.PlaneZX:
+			limit(FeedRateZ)
+			limit(FeedRateX)
+		case gcode.PlaneYZ:
+			limit(FeedRateY)
+			limit(FeedRateZ)
 		}
 	}
 	return rate
@@ -64,47 +76,44 @@ func withoutType(l gcode.Line, t byte) gcode.Line {
 
 func print(l gcode.Line) {
 	if l[0].Type != 'G' {
-		lines = append(lines, l)
+		exec(l)
 		return
 	}
 
-	if l.HasWord('Z') {
-		if absMode {
-			zPos = l.Value('Z')
-		} else {
-			zPos += l.Value('Z')
-		}
-	}
-
+	s := state.State()
 	switch l[0].Value {
-	case 21, 20:
-		setUnits = true
 	case 90:
-		if !firstAbs && absMode {
+		if !firstAbs && s.Distance == gcode.DistanceAbsolute {
 			return
 		}
-		absMode = true
 		firstAbs = false
 	case 91:
-		if !firstAbs && !absMode {
+		if !firstAbs && s.Distance == gcode.DistanceIncremental {
 			return
 		}
-		absMode = false
 		firstAbs = false
 
 	case 1, 2, 3:
 		l = withFeed(l)
 
 		f := l.Value('F')
-		if f == lastFeed {
+		if s.Units == gcode.UnitsInches {
+			// the feed rate of the state is always in mm
+			f *= Inch
+		}
+		if f == s.Feed {
 			l = withoutType(l, 'F')
-		} else if f != 0 {
-			lastFeed = f
 		}
 	}
 
-	lastNum = l[0].Value
+	exec(l)
+}
 
+func exec(l gcode.Line) {
+	err := state.Exec(l)
+	if err != nil {
+		failf("invalid gcode '%s': %v", l.String(), err)
+	}
 	lines = append(lines, l)
 }
 
@@ -182,3 +191,9 @@ func Y(val float64) gcode.Word { return gcode.Word{Type: 'Y', Value: val} }
 
 // Z is used for Z coordinates
 func Z(val float64) gcode.Word { return gcode.Word{Type: 'Z', Value: val} }
+
+// Stock will set the size of the stock (e.g. from parameters) for previews. Work zero is expected
+// at the front-left corner of the top surface.
+func Stock(width, length, thickness float64) {
+	stock = &svg.Box{Max: [3]float64{width, length, 0}, Min: [3]float64{0, 0, -thickness}}
+}
@@ -0,0 +1,190 @@
+package gcode
+
+import (
+	"errors"
+	"math"
+)
+
+// arcAngularTravelEpsilon matches Grbl's ARC_ANGULAR_TRAVEL_EPSILON, used to detect full circles.
+const arcAngularTravelEpsilon = 5e-7
+
+// Arc errors correspond to the Grbl error codes 32, 35, 33 and 34 respectively.
+var (
+	ErrArcNoAxisWords   = errors.New("arc requires at least one in-plane axis word")
+	ErrArcNoOffsets     = errors.New("arc requires at least one in-plane offset word")
+	ErrArcInvalidTarget = errors.New("invalid arc target")
+	ErrArcRadius        = errors.New("invalid arc radius")
+)
+
+// An Arc is a circular or helical move (G2, G3). All values are in mm.
+type Arc struct {
+	Start, End, Center [3]float64
+
+	Plane     Plane
+	Clockwise bool
+	Radius    float64
+
+	// Angle is the angular travel in radians. It is negative for clockwise arcs.
+	Angle float64
+}
+
+// axes returns the position indexes of the two plane axes, and the linear axis.
+func (p Plane) axes() (int, int, int) {
+	switch p {
+	case PlaneZX:
+		return 2, 0, 1
+	case PlaneYZ:
+		return 1, 2, 0
+	}
+	return 0, 1, 2
+}
+
+// offsets returns the offset words (e.g. I and J) for the plane.
+func (p Plane) offsets() (byte, byte) {
+	switch p {
+	case PlaneZX:
+		return 'K', 'I'
+	case PlaneYZ:
+		return 'J', 'K'
+	}
+	return 'I', 'J'
+}
+
+var axisWords = [3]byte{'X', 'Y', 'Z'}
+
+// NewArc will resolve the geometry of l (a G2 or G3 line) executed from the State s.
+//
+// Both center (IJK) and radius (R) formats are supported and validated the same way as Grbl.
+func NewArc(s State, l Line) (*Arc, error) {
+	in := &Interpreter{s: s}
+	err := in.Exec(l)
+	if err != nil {
+		return nil, err
+	}
+	if in.arc == nil {
+		return nil, errors.New("not an arc")
+	}
+	a := *in.arc
+	return &a, nil
+}
+
+// newArc is called with the post-execution modal state s.
+func newArc(start [3]float64, s State, l Line) (*Arc, error) {
+	a := &Arc{
+		Start:     start,
+		End:       s.Target(l),
+		Plane:     s.Plane,
+		Clockwise: s.Motion == MotionCW,
+	}
+	a0, a1, _ := s.Plane.axes()
+	if !l.HasWord(axisWords[a0]) && !l.HasWord(axisWords[a1]) {
+		return nil, ErrArcNoAxisWords
+	}
+
+	x := a.End[a0] - a.Start[a0]
+	y := a.End[a1] - a.Start[a1]
+	var off0, off1 float64
+	if l.HasWord('R') {
+		if a.End == a.Start {
+			return nil, ErrArcInvalidTarget
+		}
+		r := s.toMM(l.Value('R'))
+		hx2 := 4*r*r - x*x - y*y
+		if hx2 < 0 {
+			return nil, ErrArcRadius
+		}
+		h := -math.Sqrt(hx2) / math.Hypot(x, y)
+		if !a.Clockwise {
+			h = -h
+		}
+		if r < 0 {
+			h = -h
+			r = -r
+		}
+		off0 = 0.5 * (x - y*h)
+		off1 = 0.5 * (y + x*h)
+		a.Radius = r
+	} else {
+		w0, w1 := s.Plane.offsets()
+		if !l.HasWord(w0) && !l.HasWord(w1) {
+			return nil, ErrArcNoOffsets
+		}
+		off0 = s.toMM(l.Value(w0))
+		off1 = s.toMM(l.Value(w1))
+		a.Radius = math.Hypot(off0, off1)
+		targetR := math.Hypot(x-off0, y-off1)
+		delta := math.Abs(targetR - a.Radius)
+		if delta > 0.005 && (delta > 0.5 || delta > 0.001*a.Radius) {
+			return nil, ErrArcInvalidTarget
+		}
+	}
+
+	a.Center = a.Start
+	a.Center[a0] += off0
+	a.Center[a1] += off1
+
+	// vectors from center to start and end
+	r0, r1 := -off0, -off1
+	rt0, rt1 := x-off0, y-off1
+	a.Angle = math.Atan2(r0*rt1-r1*rt0, r0*rt0+r1*rt1)
+	if a.Clockwise {
+		if a.Angle >= -arcAngularTravelEpsilon {
+			a.Angle -= 2 * math.Pi
+		}
+	} else if a.Angle <= arcAngularTravelEpsilon {
+		a.Angle += 2 * math.Pi
+	}
+
+	return a, nil
+}
+
+// Length returns the length of the arc, including helical travel.
+func (a Arc) Length() float64 {
+	_, _, lin := a.Plane.axes()
+	return math.Hypot(a.Angle*a.Radius, a.End[lin]-a.Start[lin])
+}
+
+// Points will linearize the arc into segments that deviate from the true arc by no more
+// than tolerance (e.g. Grbl's ArcTolerance setting), returning the end point of each segment.
+//
+// The last point is always End.
+func (a Arc) Points(tolerance float64) [][3]float64 {
+	n := 0
+	if tolerance > 0 && tolerance < 2*a.Radius {
+		n = int(math.Floor(math.Abs(0.5*a.Angle*a.Radius) / math.Sqrt(tolerance*(2*a.Radius-tolerance))))
+	}
+	if n < 1 {
+		return [][3]float64{a.End}
+	}
+
+	a0, a1, lin := a.Plane.axes()
+	pts := make([][3]float64, 0, n)
+	theta := a.Angle / float64(n)
+	linear := (a.End[lin] - a.Start[lin]) / float64(n)
+	r0 := a.Start[a0] - a.Center[a0]
+	r1 := a.Start[a1] - a.Center[a1]
+	for i := 1; i < n; i++ {
+		sin, cos := math.Sincos(theta * float64(i))
+		var p [3]float64
+		p[a0] = a.Center[a0] + r0*cos - r1*sin
+		p[a1] = a.Center[a1] + r0*sin + r1*cos
+		p[lin] = a.Start[lin] + linear*float64(i)
+		pts = append(pts, p)
+	}
+	return append(pts, a.End)
+}
+
+// Lines will linearize the arc into G1 moves using absolute mm coordinates (i.e. G21 and G90 must be active).
+func (a Arc) Lines(tolerance float64) []Line {
+	pts := a.Points(tolerance)
+	lines := make([]Line, len(pts))
+	for i, p := range pts {
+		lines[i] = Line{
+			{Type: 'G', Value: 1},
+			{Type: 'X', Value: p[0]},
+			{Type: 'Y', Value: p[1]},
+			{Type: 'Z', Value: p[2]},
+		}
+	}
+	return lines
+}
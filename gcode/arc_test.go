@@ -0,0 +1,107 @@
+package gcode
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewArc(t *testing.T) {
+	test := func(name, data string, center [3]float64, angle float64) {
+		t.Run(name, func(t *testing.T) {
+			b, err := ParseLine(data)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			a, err := NewArc(NewInterpreter().State(), b.Line)
+			if err != nil {
+				t.Fatalf("err = %v; want nil", err)
+			}
+			for i := range center {
+				if math.Abs(a.Center[i]-center[i]) > 1e-9 {
+					t.Errorf("Center = %v; want %v", a.Center, center)
+					break
+				}
+			}
+			if math.Abs(a.Angle-angle) > 1e-9 {
+				t.Errorf("Angle = %f; want %f", a.Angle, angle)
+			}
+		})
+	}
+
+	test("IJK cw", "G2 X10 Y10 I10", [3]float64{10, 0, 0}, -math.Pi/2)
+	test("IJK ccw", "G3 X10 Y10 I10", [3]float64{10, 0, 0}, 3*math.Pi/2)
+	test("R cw", "G2 X10 Y10 R10", [3]float64{10, 0, 0}, -math.Pi/2)
+	test("R ccw", "G3 X10 Y10 R10", [3]float64{0, 10, 0}, math.Pi/2)
+	test("R major", "G2 X10 Y10 R-10", [3]float64{0, 10, 0}, -3*math.Pi/2)
+	test("full circle", "G2 X0 I5", [3]float64{5, 0, 0}, -2*math.Pi)
+	test("ZX plane", "G18 G2 X10 Z10 K10", [3]float64{0, 0, 10}, -math.Pi/2)
+	test("inches", "G20 G2 X1 Y1 I1", [3]float64{25.4, 0, 0}, -math.Pi/2)
+}
+
+func TestNewArc_Error(t *testing.T) {
+	test := func(data string, exp error) {
+		t.Run(data, func(t *testing.T) {
+			b, err := ParseLine(data)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			_, err = NewArc(NewInterpreter().State(), b.Line)
+			if err != exp {
+				t.Errorf("err = %v; want %v", err, exp)
+			}
+		})
+	}
+
+	test("G2 X10 Y0 I3", ErrArcInvalidTarget)
+	test("G2 X10 R2", ErrArcRadius)
+	test("G2 X0 Y0 R2", ErrArcInvalidTarget)
+	test("G2 X10", ErrArcNoOffsets)
+	test("G2 Z10 I5", ErrArcNoAxisWords)
+	test("G2 I5", ErrArcNoAxisWords)
+}
+
+func TestArc_Length(t *testing.T) {
+	b, _ := ParseLine("G2 X0 Y0 Z-3 I5")
+	a, err := NewArc(NewInterpreter().State(), b.Line)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	exp := math.Hypot(10*math.Pi, 3)
+	if math.Abs(a.Length()-exp) > 1e-9 {
+		t.Errorf("Length() = %f; want %f", a.Length(), exp)
+	}
+}
+
+func TestArc_Points(t *testing.T) {
+	b, _ := ParseLine("G3 X0 Y0 Z-2 I10")
+	a, err := NewArc(NewInterpreter().State(), b.Line)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	const tol = 0.002
+	pts := a.Points(tol)
+	if len(pts) < 2 {
+		t.Fatalf("len(Points) = %d; want > 1", len(pts))
+	}
+	if pts[len(pts)-1] != a.End {
+		t.Errorf("last point = %v; want %v", pts[len(pts)-1], a.End)
+	}
+	prev := a.Start
+	for i, p := range pts {
+		r := math.Hypot(p[0]-a.Center[0], p[1]-a.Center[1])
+		if math.Abs(r-a.Radius) > 1e-9 {
+			t.Fatalf("point %d radius = %f; want %f", i, r, a.Radius)
+		}
+		// chord midpoint must be within tolerance of the arc (Grbl's segment count is an approximation)
+		mid := math.Hypot((p[0]+prev[0])/2-a.Center[0], (p[1]+prev[1])/2-a.Center[1])
+		if a.Radius-mid > tol*1.01 {
+			t.Fatalf("segment %d deviation = %f; want <= %f", i, a.Radius-mid, tol)
+		}
+		prev = p
+	}
+
+	if len(a.Lines(tol)) != len(pts) {
+		t.Errorf("len(Lines) = %d; want %d", len(a.Lines(tol)), len(pts))
+	}
+}
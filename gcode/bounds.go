@@ -0,0 +1,145 @@
+package gcode
+
+import (
+	"fmt"
+	"math"
+)
+
+// boundsArcTolerance is the arc tolerance used to find the extent of arcs, in mm.
+const boundsArcTolerance = 0.002
+
+// Extent is the range of positions reached by a single line, in mm. Axes with an unknown
+// position (e.g. not yet given by the program) are NaN.
+type Extent struct {
+	Min, Max [3]float64
+
+	// Machine is set for moves in machine coordinates (G53).
+	Machine bool
+}
+
+// Extents will return the Extent of each line of the program (in the coordinate system of the line),
+// or nil for lines without motion. The starting position of a move is not included, as it is the
+// end of the move before.
+//
+// Stored positions (G28, G30) are not known, so only their intermediate point is included.
+func Extents(lines []Line) ([]*Extent, error) {
+	in := NewInterpreter()
+	res := make([]*Extent, len(lines))
+
+	// axes are unknown until given in absolute mode
+	var known [3]bool
+	for n, l := range lines {
+		err := in.Exec(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		s := in.State()
+		machine := hasG(l, 53)
+		abs := setsPosition(l, s) || isMove(l) && s.Distance == DistanceAbsolute
+		given := known
+		if machine {
+			given = [3]bool{}
+		}
+		for i := range known {
+			if !l.HasWord("XYZ"[i]) {
+				continue
+			}
+			switch {
+			case machine:
+				// the Interpreter doesn't know the work position after a move in machine coordinates
+				given[i], known[i] = true, false
+			case abs:
+				given[i], known[i] = true, true
+			}
+		}
+		if !isMove(l) {
+			continue
+		}
+
+		e := &Extent{Min: s.Position, Max: s.Position, Machine: machine}
+		if a := in.Arc(); a != nil {
+			for _, p := range a.Points(boundsArcTolerance) {
+				for i := range p {
+					e.Min[i] = math.Min(e.Min[i], p[i])
+					e.Max[i] = math.Max(e.Max[i], p[i])
+				}
+			}
+		}
+		for i := range given {
+			if !given[i] {
+				e.Min[i], e.Max[i] = math.NaN(), math.NaN()
+			}
+		}
+		res[n] = e
+	}
+	return res, nil
+}
+
+// updateKnown will update which axes have a known position after l, where s is the state after it.
+// Axes become known when moved to in absolute mode or set (see setsPosition), and unknown after a move
+// in machine coordinates (G53) or to a stored position (G28, G30), as the Interpreter doesn't know the
+// work position after them.
+func updateKnown(known *[3]bool, l Line, s State) {
+	stored := hasG(l, 28) || hasG(l, 30)
+	axes := l.HasWord('X') || l.HasWord('Y') || l.HasWord('Z')
+	for i := range known {
+		given := l.HasWord("XYZ"[i])
+		switch {
+		case hasG(l, 53) && given, stored && (given || !axes):
+			known[i] = false
+		case given && (setsPosition(l, s) || isMove(l) && s.Distance == DistanceAbsolute):
+			known[i] = true
+		}
+	}
+}
+
+// setsPosition reports if the axis words of l set the current position (G92, or G10 L20 on the
+// active coordinate system), where s is the state after it.
+func setsPosition(l Line, s State) bool {
+	if hasG(l, 92) {
+		return true
+	}
+	p := int(l.Value('P'))
+	return hasG(l, 10) && l.Value('L') == 20 && (p == 0 || p+53 == s.WCS)
+}
+
+// Bounds will return the minimum and maximum position (in mm, in the coordinate system of
+// each line) reached by the program. The starting position is not included unless a line
+// ends there, and moves in machine coordinates (G53) are ignored.
+func Bounds(lines []Line) (min, max [3]float64, err error) {
+	ext, err := Extents(lines)
+	if err != nil {
+		return min, max, err
+	}
+	var set [3]bool
+	for _, e := range ext {
+		if e == nil || e.Machine {
+			continue
+		}
+		for i := range min {
+			switch {
+			case math.IsNaN(e.Min[i]):
+			case !set[i]:
+				min[i], max[i], set[i] = e.Min[i], e.Max[i], true
+			default:
+				min[i] = math.Min(min[i], e.Min[i])
+				max[i] = math.Max(max[i], e.Max[i])
+			}
+		}
+	}
+	return min, max, nil
+}
+
+func hasG(l Line, v float64) bool {
+	for _, w := range l {
+		if w.Type == 'G' && w.Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isMove returns true if the axis words of l describe a move.
+func isMove(l Line) bool {
+	return hasAxis(l) && !hasG(l, 10) && !hasG(l, 92) && !hasG(l, 43.1)
+}
@@ -0,0 +1,195 @@
+package gcode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// levelArcTolerance is the arc tolerance used when linearizing arcs to be leveled, in mm.
+const levelArcTolerance = 0.002
+
+// HeightMap is a grid of Z measurements of a surface, used to level a program to follow it
+// (e.g. when engraving PCBs). All values are in mm, in work coordinates.
+type HeightMap struct {
+	// X and Y are the position of the first point.
+	X, Y float64
+
+	// StepX and StepY are the distances between points.
+	StepX, StepY float64
+
+	Cols, Rows int
+
+	// Z holds the measurements row by row, starting at Y.
+	Z []float64
+}
+
+func gridSize(min, max, step float64) (int, float64) {
+	n := int(math.Ceil((max-min)/step)) + 1
+	if n < 2 {
+		return 1, 0
+	}
+	return n, (max - min) / float64(n-1)
+}
+
+// NewHeightMap will create an empty HeightMap covering the rectangle from min to max (X and Y),
+// with points no more than step apart.
+func NewHeightMap(min, max [2]float64, step float64) *HeightMap {
+	h := &HeightMap{X: min[0], Y: min[1]}
+	h.Cols, h.StepX = gridSize(min[0], max[0], step)
+	h.Rows, h.StepY = gridSize(min[1], max[1], step)
+	h.Z = make([]float64, h.Cols*h.Rows)
+	return h
+}
+
+// Point returns the X and Y position of a point in the grid.
+func (h *HeightMap) Point(col, row int) (x, y float64) {
+	return h.X + float64(col)*h.StepX, h.Y + float64(row)*h.StepY
+}
+
+// cell returns the index of the cell containing v, and the fractional position within it.
+func cell(v, start, step float64, n int) (int, float64) {
+	if n < 2 || step == 0 {
+		return 0, 0
+	}
+	f := math.Max(0, math.Min((v-start)/step, float64(n-1)))
+	i := int(f)
+	if i == n-1 {
+		i--
+	}
+	return i, f - float64(i)
+}
+
+// At returns the height of the surface at x, y using bilinear interpolation. Positions
+// outside the grid use the value at the nearest edge.
+func (h *HeightMap) At(x, y float64) float64 {
+	if len(h.Z) == 0 {
+		return 0
+	}
+	c, tx := cell(x, h.X, h.StepX, h.Cols)
+	r, ty := cell(y, h.Y, h.StepY, h.Rows)
+	c1, r1 := c, r
+	if h.Cols > 1 {
+		c1++
+	}
+	if h.Rows > 1 {
+		r1++
+	}
+	z := func(c, r int) float64 { return h.Z[r*h.Cols+c] }
+	lerp := func(a, b, t float64) float64 { return a + (b-a)*t }
+
+	return lerp(
+		lerp(z(c, r), z(c1, r), tx),
+		lerp(z(c, r1), z(c1, r1), tx),
+		ty,
+	)
+}
+
+// Level will rewrite lines to follow the surface of the HeightMap by adding its height to
+// the Z position of each move. Feed moves are split so that no segment is longer than segment
+// (in mm) in X and Y, and arcs are converted to line segments.
+//
+// Moves in machine coordinates (G53), to stored positions (G28, G30) and probing moves
+// are left unchanged, as are moves until the X, Y and Z positions are known (i.e. given in
+// absolute mode). Inverse time feed mode (G93) is not supported.
+func (h *HeightMap) Level(lines []Line, segment float64) ([]Line, error) {
+	if len(h.Z) != h.Cols*h.Rows {
+		return nil, errors.New("height map has the wrong number of points")
+	}
+
+	in := NewInterpreter()
+	out := make([]Line, 0, len(lines))
+
+	// axes are unknown until given in absolute mode, as the Interpreter starts at 0,0,0
+	var known [3]bool
+	for n, l := range lines {
+		start := in.State().Position
+		err := in.Exec(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		s := in.State()
+
+		wasKnown := known[0] && known[1] && known[2]
+		updateKnown(&known, l, s)
+
+		switch {
+		case !isMove(l), hasG(l, 53), hasG(l, 28), hasG(l, 30):
+			out = append(out, l)
+			continue
+		case s.Motion != MotionRapid && s.Motion != MotionLinear && s.Motion != MotionCW && s.Motion != MotionCCW:
+			out = append(out, l)
+			continue
+		case s.FeedMode == FeedInverseTime:
+			return nil, fmt.Errorf("line %d: inverse time feed mode is not supported", n+1)
+		case !wasKnown || !known[0] || !known[1] || !known[2]:
+			out = append(out, l)
+			continue
+		}
+
+		// keep everything but the motion itself
+		var rest Line
+		for _, w := range l {
+			switch {
+			case w.Type == 'G' && (w.Value == MotionRapid || w.Value == MotionLinear || w.Value == MotionCW || w.Value == MotionCCW):
+			case axisIndex(w.Type) != -1, w.Type == 'I', w.Type == 'J', w.Type == 'K', w.Type == 'R':
+			default:
+				rest = append(rest, w)
+			}
+		}
+		if len(rest) > 0 {
+			out = append(out, rest)
+		}
+
+		// segments are always in absolute mm
+		modes := s.Units != UnitsMillimeters || s.Distance != DistanceAbsolute
+		if modes {
+			out = append(out, Line{{Type: 'G', Value: 21}, {Type: 'G', Value: 90}})
+		}
+
+		pts := [][3]float64{s.Position}
+		if a := in.Arc(); a != nil {
+			pts = a.Points(levelArcTolerance)
+		}
+		motion := float64(MotionLinear)
+		if s.Motion == MotionRapid {
+			motion = MotionRapid
+		}
+		from := start
+		for _, p := range pts {
+			steps := 1
+			if motion != MotionRapid && segment > 0 {
+				steps = int(math.Ceil(math.Hypot(p[0]-from[0], p[1]-from[1]) / segment))
+				if steps < 1 {
+					steps = 1
+				}
+			}
+			for i := 1; i <= steps; i++ {
+				t := float64(i) / float64(steps)
+				x := from[0] + (p[0]-from[0])*t
+				y := from[1] + (p[1]-from[1])*t
+				z := from[2] + (p[2]-from[2])*t
+				out = append(out, Line{
+					{Type: 'G', Value: motion},
+					{Type: 'X', Value: x},
+					{Type: 'Y', Value: y},
+					{Type: 'Z', Value: z + h.At(x, y)},
+				})
+			}
+			from = p
+		}
+
+		if modes {
+			var restore Line
+			if s.Units == UnitsInches {
+				restore = append(restore, Word{Type: 'G', Value: 20})
+			}
+			if s.Distance == DistanceIncremental {
+				restore = append(restore, Word{Type: 'G', Value: 91})
+			}
+			out = append(out, restore)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,121 @@
+package gcode
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestHeightMap_At(t *testing.T) {
+	h := NewHeightMap([2]float64{0, 0}, [2]float64{10, 5}, 4)
+	if h.Cols != 4 || h.Rows != 3 {
+		t.Fatalf("size = %dx%d; want 4x3", h.Cols, h.Rows)
+	}
+	x, y := h.Point(1, 2)
+	if math.Abs(x-10.0/3) > 1e-9 || y != 5 {
+		t.Errorf("Point(1,2) = %f,%f; want 3.333,5", x, y)
+	}
+
+	// a plane sloping up along X and Y
+	for r := 0; r < h.Rows; r++ {
+		for c := 0; c < h.Cols; c++ {
+			x, y := h.Point(c, r)
+			h.Z[r*h.Cols+c] = x*0.1 + y*0.01
+		}
+	}
+	check := func(x, y, exp float64) {
+		t.Helper()
+		if z := h.At(x, y); math.Abs(z-exp) > 1e-9 {
+			t.Errorf("At(%g,%g) = %f; want %f", x, y, z, exp)
+		}
+	}
+	check(0, 0, 0)
+	check(5, 2, 0.52)
+	check(10, 5, 1.05)
+	check(20, -5, 1)
+}
+
+func TestHeightMap_Level(t *testing.T) {
+	h := NewHeightMap([2]float64{0, 0}, [2]float64{10, 10}, 10)
+	copy(h.Z, []float64{0, 1, 0, 1})
+
+	lines := parseLines(t, `
+G21 G90 G0 Z1
+G0 X0 Y0
+G1 Z-0.1 F100
+X10
+G20 G91 Y0.1
+G53 G0 Z0
+`)
+	out, err := h.Level(lines, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := make([]string, len(out))
+	for i, l := range out {
+		s[i] = l.String()
+	}
+	exp := []string{
+		"G21G90G0Z1",
+		"G0X0Y0",
+		"F100",
+		"G1X0Y0Z-0.1",
+		"G1X5Y0Z0.4",
+		"G1X10Y0Z0.9",
+		"G20G91",
+		"G21G90",
+		"G1X10Y2.54Z0.9",
+		"G20G91",
+		"G53G0Z0",
+	}
+	if strings.Join(s, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(s, "\n"), strings.Join(exp, "\n"))
+	}
+
+	// no move to the assumed origin before X and Y are given
+	out, err = h.Level(parseLines(t, "G21 G90\nG0 Z5\nG0 X10 Y10\nG0 X0\n"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = s[:0]
+	for _, l := range out {
+		s = append(s, l.String())
+	}
+	exp = []string{"G21G90", "G0Z5", "G0X10Y10", "G0X0Y10Z5"}
+	if strings.Join(s, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(s, "\n"), strings.Join(exp, "\n"))
+	}
+
+	// changing a stored offset doesn't set the position
+	out, err = h.Level(parseLines(t, "G21 G91\nG10 L2 P1 X0 Y0 Z0\nG0 X1 Y1 Z1\n"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = s[:0]
+	for _, l := range out {
+		s = append(s, l.String())
+	}
+	exp = []string{"G21G91", "G10L2P1X0Y0Z0", "G0X1Y1Z1"}
+	if strings.Join(s, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(s, "\n"), strings.Join(exp, "\n"))
+	}
+
+	_, err = h.Level(parseLines(t, "G93 G1 X1 F1\n"), 5)
+	if err == nil {
+		t.Error("Level(G93) err = nil; want error")
+	}
+}
+
+func TestBounds(t *testing.T) {
+	min, max, err := Bounds(parseLines(t, `
+G0 X5 Y5
+G2 X15 Y5 I5 J0
+G92 X100
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min != [3]float64{5, 5, 0} || math.Abs(max[1]-10) > 0.01 || max[0] != 15 {
+		t.Errorf("bounds = %v, %v; want [5 5 0], [15 10 0]", min, max)
+	}
+}
@@ -0,0 +1,369 @@
+package gcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Interpreter errors correspond to the Grbl error codes 21 and 31 respectively.
+var (
+	ErrModalViolation  = errors.New("modal group violation")
+	ErrAxisWordsUnused = errors.New("axis words with no motion mode")
+)
+
+// Plane is the active plane for arcs (G17, G18, G19).
+type Plane int
+
+// Planes
+const (
+	PlaneXY Plane = iota
+	PlaneZX
+	PlaneYZ
+)
+
+// Units is the active unit mode (G20, G21).
+type Units int
+
+// Units
+const (
+	UnitsMillimeters Units = iota
+	UnitsInches
+)
+
+// DistanceMode controls how axis words are interpreted (G90, G91).
+type DistanceMode int
+
+// Distance modes
+const (
+	DistanceAbsolute DistanceMode = iota
+	DistanceIncremental
+)
+
+// FeedMode controls how the F word is interpreted (G93, G94).
+type FeedMode int
+
+// Feed modes
+const (
+	FeedUnitsPerMinute FeedMode = iota
+	FeedInverseTime
+)
+
+// SpindleState is the spindle mode (M3, M4, M5).
+type SpindleState int
+
+// Spindle states
+const (
+	SpindleOff SpindleState = iota
+	SpindleCW
+	SpindleCCW
+)
+
+// Motion modes
+const (
+	MotionRapid  = 0
+	MotionLinear = 1
+	MotionCW     = 2
+	MotionCCW    = 3
+	MotionCancel = 80
+)
+
+// State is the complete modal state of a machine, along with
+// its position.
+type State struct {
+	// Motion is the active motion mode (e.g. 0, 1, 2, 3, 38.2, or 80).
+	Motion   float64
+	Plane    Plane
+	Units    Units
+	Distance DistanceMode
+	FeedMode FeedMode
+
+	// WCS is the active work coordinate system, 54-59.
+	WCS int
+
+	Tool         int
+	Spindle      SpindleState
+	SpindleSpeed float64
+	Mist, Flood  bool
+
+	// Feed is the feed rate in mm/min, or the inverse time value when FeedMode is FeedInverseTime.
+	Feed float64
+
+	// Position is the absolute position, in mm, of the X, Y, and Z axes in the current
+	// coordinate system.
+	Position [3]float64
+
+	// G92 is the current G92 offset, in mm.
+	G92 [3]float64
+
+	// ToolLengthOffset is the dynamic tool length offset set by G43.1, in mm.
+	ToolLengthOffset float64
+}
+
+// An Interpreter tracks the modal state and position as Lines are executed.
+//
+// Work coordinate system offsets and stored positions (e.g. G28) are not known to
+// the Interpreter, so changing the active WCS or moving to a stored position will
+// not affect Position beyond any intermediate point given.
+type Interpreter struct {
+	s   State
+	arc *Arc
+}
+
+// NewInterpreter creates a new Interpreter using the power-on defaults of Grbl.
+func NewInterpreter() *Interpreter {
+	return &Interpreter{s: State{
+		Motion: MotionRapid,
+		WCS:    54,
+	}}
+}
+
+// State will return the current State.
+func (i *Interpreter) State() State {
+	return i.s
+}
+
+// SetState will replace the current State (e.g. to synchronize Position with a machine).
+func (i *Interpreter) SetState(s State) {
+	i.s = s
+	i.arc = nil
+}
+
+// Arc will return the geometry of the last executed line if it was an arc, otherwise nil.
+func (i *Interpreter) Arc() *Arc {
+	return i.arc
+}
+
+// Interpret will execute all lines with a new Interpreter, returning the State after each.
+func Interpret(lines []Line) ([]State, error) {
+	in := NewInterpreter()
+	states := make([]State, len(lines))
+	for n, l := range lines {
+		err := in.Exec(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		states[n] = in.State()
+	}
+	return states, nil
+}
+
+// axisIndex returns the position index of an axis word, or -1.
+func axisIndex(t byte) int {
+	switch t {
+	case 'X':
+		return 0
+	case 'Y':
+		return 1
+	case 'Z':
+		return 2
+	}
+	return -1
+}
+
+func (s State) toMM(v float64) float64 {
+	if s.Units == UnitsInches {
+		return v * 25.4
+	}
+	return v
+}
+
+// Target will return the end position of the line's axis words given the current State.
+// Axes without words will remain unchanged.
+func (s State) Target(l Line) [3]float64 {
+	t := s.Position
+	for _, w := range l {
+		idx := axisIndex(w.Type)
+		if idx == -1 {
+			continue
+		}
+		if s.Distance == DistanceIncremental {
+			t[idx] += s.toMM(w.Value)
+		} else {
+			t[idx] = s.toMM(w.Value)
+		}
+	}
+	return t
+}
+
+func hasAxis(l Line) bool {
+	for _, w := range l {
+		if axisIndex(w.Type) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Exec will update the State by executing the Line.
+//
+// An error is returned for unsupported or invalid commands, in which case the State
+// is not modified.
+func (i *Interpreter) Exec(l Line) error {
+	s := i.s
+	nonModal := -1.0
+	motion := -1.0
+	var absOverride bool
+	var tlo bool
+	var arc *Arc
+
+	for _, w := range l {
+		switch w.Type {
+		case 'G':
+			switch w.Value {
+			case 0, 1, 2, 3, 38.2, 38.3, 38.4, 38.5, 80:
+				if motion != -1 {
+					return fmt.Errorf("multiple motion commands: %w", ErrModalViolation)
+				}
+				motion = w.Value
+			case 4, 10, 28, 30, 28.1, 30.1, 92, 92.1:
+				if nonModal != -1 {
+					return fmt.Errorf("multiple non-modal commands: %w", ErrModalViolation)
+				}
+				nonModal = w.Value
+			case 53:
+				absOverride = true
+			case 17:
+				s.Plane = PlaneXY
+			case 18:
+				s.Plane = PlaneZX
+			case 19:
+				s.Plane = PlaneYZ
+			case 20:
+				s.Units = UnitsInches
+			case 21:
+				s.Units = UnitsMillimeters
+			case 90:
+				s.Distance = DistanceAbsolute
+			case 91:
+				s.Distance = DistanceIncremental
+			case 91.1, 61, 40:
+				// IJK are always incremental, exact path and no cutter comp are the only modes
+			case 93:
+				s.FeedMode = FeedInverseTime
+			case 94:
+				s.FeedMode = FeedUnitsPerMinute
+			case 54, 55, 56, 57, 58, 59:
+				s.WCS = int(w.Value)
+			case 43.1:
+				tlo = true
+			case 49:
+				s.ToolLengthOffset = 0
+			default:
+				return fmt.Errorf("unsupported command %s", w.String())
+			}
+		case 'M':
+			switch w.Value {
+			case 0, 1, 6:
+			case 2, 30:
+				s.Motion = MotionLinear
+				s.Plane = PlaneXY
+				s.Distance = DistanceAbsolute
+				s.FeedMode = FeedUnitsPerMinute
+				s.WCS = 54
+				s.Spindle = SpindleOff
+				s.Mist, s.Flood = false, false
+			case 3:
+				s.Spindle = SpindleCW
+			case 4:
+				s.Spindle = SpindleCCW
+			case 5:
+				s.Spindle = SpindleOff
+			case 7:
+				s.Mist = true
+			case 8:
+				s.Flood = true
+			case 9:
+				s.Mist, s.Flood = false, false
+			default:
+				return fmt.Errorf("unsupported command %s", w.String())
+			}
+		case 'T':
+			s.Tool = int(w.Value)
+		case 'S':
+			s.SpindleSpeed = w.Value
+		}
+	}
+
+	// F is applied after units are known
+	for _, w := range l {
+		if w.Type != 'F' {
+			continue
+		}
+		if s.FeedMode == FeedInverseTime {
+			s.Feed = w.Value
+		} else {
+			s.Feed = s.toMM(w.Value)
+		}
+	}
+
+	if tlo {
+		if !l.HasWord('Z') {
+			return fmt.Errorf("G43.1 requires a Z value")
+		}
+		s.ToolLengthOffset = s.toMM(l.Value('Z'))
+	}
+
+	if motion != -1 {
+		s.Motion = motion
+	}
+
+	switch nonModal {
+	case 92:
+		for _, w := range l {
+			idx := axisIndex(w.Type)
+			if idx == -1 {
+				continue
+			}
+			v := s.toMM(w.Value)
+			s.G92[idx] += s.Position[idx] - v
+			s.Position[idx] = v
+		}
+	case 92.1:
+		for idx := range s.Position {
+			s.Position[idx] += s.G92[idx]
+			s.G92[idx] = 0
+		}
+	case 10:
+		// G10 L20 sets the current position in the given (or active) coordinate system.
+		p := int(l.Value('P'))
+		if l.Value('L') == 20 && (p == 0 || p+53 == s.WCS) {
+			for _, w := range l {
+				idx := axisIndex(w.Type)
+				if idx != -1 {
+					s.Position[idx] = s.toMM(w.Value)
+				}
+			}
+		}
+	case 28, 30:
+		// only the intermediate point is known
+		s.Position = s.Target(l)
+	case -1:
+		if (motion == MotionCW || motion == MotionCCW) && !hasAxis(l) {
+			return ErrArcNoAxisWords
+		}
+		if !hasAxis(l) || tlo {
+			break
+		}
+		if s.Motion == MotionCancel {
+			return ErrAxisWordsUnused
+		}
+		if s.Motion == MotionCW || s.Motion == MotionCCW {
+			var err error
+			arc, err = newArc(s.Position, s, l)
+			if err != nil {
+				return err
+			}
+			s.Position = arc.End
+		} else if absOverride {
+			abs := s
+			abs.Distance = DistanceAbsolute
+			s.Position = abs.Target(l)
+		} else {
+			s.Position = s.Target(l)
+		}
+	}
+
+	i.s = s
+	i.arc = arc
+	return nil
+}
@@ -0,0 +1,102 @@
+package gcode
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func parseLines(t *testing.T, data string) []Line {
+	blocks, err := Parse(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return Lines(blocks)
+}
+
+func TestInterpreter(t *testing.T) {
+	lines := parseLines(t, `
+G21 G90 G17
+G0 X10 Y5
+G91 G1 X5 Z-1 F100
+G20 X1
+G90 G18 G55 T2 M3 S1000 M8
+G93 G1 X0 F2
+G92 X1 Y1
+M2
+`)
+	states, err := Interpret(lines)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+
+	checkPos := func(n int, exp [3]float64) {
+		if states[n].Position != exp {
+			t.Errorf("line %d: Position = %v; want %v", n+1, states[n].Position, exp)
+		}
+	}
+
+	checkPos(1, [3]float64{10, 5, 0})
+	checkPos(2, [3]float64{15, 5, -1})
+	checkPos(3, [3]float64{40.4, 5, -1})
+
+	if states[2].Feed != 100 {
+		t.Errorf("Feed = %f; want 100", states[2].Feed)
+	}
+	if states[2].Distance != DistanceIncremental {
+		t.Errorf("Distance = %d; want DistanceIncremental", states[2].Distance)
+	}
+	if states[3].Units != UnitsInches {
+		t.Errorf("Units = %d; want UnitsInches", states[3].Units)
+	}
+
+	s := states[4]
+	if s.Plane != PlaneZX || s.WCS != 55 || s.Tool != 2 || s.Spindle != SpindleCW || s.SpindleSpeed != 1000 || !s.Flood {
+		t.Errorf("modal state = %+v", s)
+	}
+
+	if states[5].FeedMode != FeedInverseTime || states[5].Feed != 2 {
+		t.Errorf("FeedMode = %d, Feed = %f; want FeedInverseTime, 2", states[5].FeedMode, states[5].Feed)
+	}
+	checkPos(5, [3]float64{0, 5, -1})
+
+	checkPos(6, [3]float64{25.4, 25.4, -1})
+	if states[6].G92 != [3]float64{-25.4, -20.4, 0} {
+		t.Errorf("G92 = %v; want [-25.4 -20.4 0]", states[6].G92)
+	}
+
+	s = states[7]
+	if s.WCS != 54 || s.Spindle != SpindleOff || s.Flood || s.Distance != DistanceAbsolute || s.FeedMode != FeedUnitsPerMinute {
+		t.Errorf("program end state = %+v", s)
+	}
+}
+
+func TestInterpreter_Error(t *testing.T) {
+	// want is the expected error, or nil for any
+	test := func(name, data string, want error) {
+		t.Run(name, func(t *testing.T) {
+			in := NewInterpreter()
+			b, err := ParseLine(data)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			before := in.State()
+			err = in.Exec(b.Line)
+			if err == nil {
+				t.Fatal("err = nil; want error")
+			}
+			if want != nil && !errors.Is(err, want) {
+				t.Errorf("err = %v; want %v", err, want)
+			}
+			if in.State() != before {
+				t.Error("state was modified")
+			}
+		})
+	}
+
+	test("unsupported", "G0 G41 X1", nil)
+	test("multiple motion", "G0 G1 X1", ErrModalViolation)
+	test("multiple non-modal", "G4 G92 X1", ErrModalViolation)
+	test("no motion mode", "G80 X1", ErrAxisWordsUnused)
+	test("G43.1 without Z", "G43.1", nil)
+}
@@ -0,0 +1,253 @@
+package gcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Pos represents a position within G-code source text.
+type Pos struct{ Line, Col int }
+
+// A Block is a single line of G-code source, along with the
+// non-functional data that accompanied it.
+type Block struct {
+	// Line holds the words of the block. Line numbers and checksums are
+	// not included.
+	Line Line
+
+	// Number is the value of the `N` word, or -1 if not present.
+	Number int
+
+	// Comments contains the text of any comments, without delimiters.
+	Comments []string
+
+	// Delete is true if the block began with the block-delete character `/`.
+	Delete bool
+
+	// Checksum is the value following `*` at the end of the block, or -1 if not present.
+	// It is verified by the parser.
+	Checksum int
+
+	// OWord holds the raw text of an O-code (e.g. `o100 sub` or `o<probe> call`).
+	OWord string
+
+	Pos Pos
+}
+
+// SyntaxError is returned when G-code source text cannot be parsed.
+type SyntaxError struct {
+	Pos Pos
+	Msg string
+}
+
+// Error implements the error interface.
+func (s SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at line %d col %d: %s", s.Pos.Line, s.Pos.Col, s.Msg)
+}
+
+type lineParser struct {
+	s   string
+	i   int
+	pos Pos
+}
+
+func (p *lineParser) errorf(col int, format string, args ...interface{}) error {
+	return &SyntaxError{
+		Pos: Pos{Line: p.pos.Line, Col: p.pos.Col + col},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r'
+}
+
+func (p *lineParser) skipSpace() {
+	for p.i < len(p.s) && isSpace(p.s[p.i]) {
+		p.i++
+	}
+}
+
+// number will read a numeric value, ignoring any embedded whitespace.
+func (p *lineParser) number() (string, error) {
+	p.skipSpace()
+	start := p.i
+	var buf []byte
+loop:
+	for ; p.i < len(p.s); p.i++ {
+		c := p.s[p.i]
+		switch {
+		case isSpace(c):
+		case c >= '0' && c <= '9', c == '.':
+			buf = append(buf, c)
+		case (c == '-' || c == '+') && len(buf) == 0:
+			buf = append(buf, c)
+		default:
+			break loop
+		}
+	}
+	if len(buf) == 0 {
+		return "", p.errorf(start, "expected a numeric value")
+	}
+	return string(buf), nil
+}
+
+// checksumIndex returns the index of the `*` preceding a checksum, ignoring
+// any that appear within comments.
+func checksumIndex(s string) int {
+	var paren bool
+	for i := 0; i < len(s); i++ {
+		switch {
+		case paren:
+			paren = s[i] != ')'
+		case s[i] == '(':
+			paren = true
+		case s[i] == ';':
+			return -1
+		case s[i] == '*':
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *lineParser) parse() (*Block, error) {
+	b := &Block{Number: -1, Checksum: -1, Pos: p.pos}
+
+	if idx := checksumIndex(p.s); idx != -1 {
+		sum, err := strconv.Atoi(strings.TrimSpace(p.s[idx+1:]))
+		if err != nil {
+			return nil, p.errorf(idx+1, "invalid checksum '%s'", strings.TrimSpace(p.s[idx+1:]))
+		}
+		var calc int
+		for i := 0; i < idx; i++ {
+			calc ^= int(p.s[i])
+		}
+		if calc != sum {
+			return nil, p.errorf(idx, "checksum mismatch: got %d, calculated %d", sum, calc)
+		}
+		b.Checksum = sum
+		p.s = p.s[:idx]
+	}
+
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == '/' {
+		b.Delete = true
+		p.i++
+	}
+
+	for {
+		p.skipSpace()
+		if p.i >= len(p.s) {
+			break
+		}
+		start := p.i
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == ';':
+			b.Comments = append(b.Comments, strings.TrimSpace(p.s[p.i:]))
+			p.i = len(p.s)
+			continue
+		case c == '(':
+			end := strings.IndexByte(p.s[p.i:], ')')
+			if end == -1 {
+				return nil, p.errorf(start, "unterminated comment")
+			}
+			b.Comments = append(b.Comments, strings.TrimSpace(p.s[p.i:p.i+end]))
+			p.i += end + 1
+			continue
+		case c == '%' && len(b.Line) == 0 && b.Number == -1:
+			// program delimiter
+			continue
+		case c == 'o' || c == 'O':
+			end := strings.IndexAny(p.s[p.i:], ";(")
+			if end == -1 {
+				end = len(p.s) - p.i
+			}
+			b.OWord = strings.TrimSpace(p.s[start : p.i+end])
+			p.i += end
+			continue
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z':
+		default:
+			return nil, p.errorf(start, "unexpected character '%c'", c)
+		}
+
+		lit, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(lit, 64)
+		if err != nil {
+			return nil, p.errorf(start+1, "invalid number '%s'", lit)
+		}
+		if c == 'N' {
+			if b.Number != -1 {
+				return nil, p.errorf(start, "multiple line numbers")
+			}
+			b.Number = int(v)
+			continue
+		}
+		b.Line = append(b.Line, Word{Type: c, Value: v})
+	}
+
+	return b, nil
+}
+
+// ParseLine will parse a single line of G-code.
+//
+// Words are case-insensitive and whitespace is ignored. Comments, line numbers,
+// block-delete and checksums are returned as part of the Block rather than the Line.
+func ParseLine(s string) (*Block, error) {
+	p := &lineParser{s: s, pos: Pos{Line: 1, Col: 1}}
+	return p.parse()
+}
+
+// Parse will read and parse all G-code from the io.Reader. Blank lines
+// are omitted.
+func Parse(r io.Reader) ([]Block, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), 1024*1024)
+
+	var blocks []Block
+	var n int
+	for s.Scan() {
+		n++
+		line := s.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p := &lineParser{s: line, pos: Pos{Line: n, Col: 1}}
+		b, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		if len(b.Line) == 0 && len(b.Comments) == 0 && b.OWord == "" {
+			continue
+		}
+		blocks = append(blocks, *b)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// Lines returns the executable Lines from a set of Blocks. Blocks
+// without words (e.g. comment-only) and block-deleted lines are omitted.
+func Lines(blocks []Block) []Line {
+	lines := make([]Line, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Delete || len(b.Line) == 0 {
+			continue
+		}
+		lines = append(lines, b.Line)
+	}
+	return lines
+}
@@ -0,0 +1,125 @@
+package gcode
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	test := func(data, exp string, f func(t *testing.T, b *Block)) {
+		t.Run(data, func(t *testing.T) {
+			b, err := ParseLine(data)
+			if err != nil {
+				t.Fatalf("err = %v; want nil", err)
+			}
+			if b.Line.String() != exp {
+				t.Errorf("Line = %s; want %s", b.Line.String(), exp)
+			}
+			if f != nil {
+				f(t, b)
+			}
+		})
+	}
+
+	test("G21", "G21", nil)
+	test("g0 x1 y-2.5", "G0X1Y-2.5", nil)
+	test("G1 X 1 0 . 5", "G1X10.5", nil)
+	test("N10 G0 X1", "G0X1", func(t *testing.T, b *Block) {
+		if b.Number != 10 {
+			t.Errorf("Number = %d; want 10", b.Number)
+		}
+	})
+	test("G0 (rapid) X1 ; to x1", "G0X1", func(t *testing.T, b *Block) {
+		if len(b.Comments) != 2 {
+			t.Fatalf("len(Comments) = %d; want 2", len(b.Comments))
+		}
+		if b.Comments[0] != "rapid" {
+			t.Errorf("Comments[0] = %s; want rapid", strconv.Quote(b.Comments[0]))
+		}
+		if b.Comments[1] != "to x1" {
+			t.Errorf("Comments[1] = %s; want %s", strconv.Quote(b.Comments[1]), strconv.Quote("to x1"))
+		}
+	})
+	test("/G0 X1", "G0X1", func(t *testing.T, b *Block) {
+		if !b.Delete {
+			t.Error("Delete = false; want true")
+		}
+	})
+	test("N3 T5 M6*103", "T5M6", func(t *testing.T, b *Block) {
+		if b.Checksum != 103 {
+			t.Errorf("Checksum = %d; want 103", b.Checksum)
+		}
+	})
+	test("(*** header ***)", "", nil)
+	test("%", "", nil)
+	test("o100 sub", "", func(t *testing.T, b *Block) {
+		if b.OWord != "o100 sub" {
+			t.Errorf("OWord = %s; want %s", strconv.Quote(b.OWord), strconv.Quote("o100 sub"))
+		}
+	})
+}
+
+func TestParseLine_Error(t *testing.T) {
+	test := func(data string, col int) {
+		t.Run(data, func(t *testing.T) {
+			_, err := ParseLine(data)
+			serr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("err = %v; want %T", err, &SyntaxError{})
+			}
+			if serr.Pos.Col != col {
+				t.Errorf("Col = %d; want %d", serr.Pos.Col, col)
+			}
+		})
+	}
+
+	test("G0 X", 5)
+	test("G0 X1 #", 7)
+	test("G0 (oops", 4)
+	test("N1 N2", 4)
+	test("G0X1*1", 5)
+	test("G0 X1.2.3", 5)
+}
+
+func TestParse(t *testing.T) {
+	data := `%
+(job header)
+G21 G90
+/M8
+
+G0 X1 Y2
+G1 Z-1 F100 ; plunge
+%
+`
+	blocks, err := Parse(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if len(blocks) != 5 {
+		t.Fatalf("len(blocks) = %d; want 5", len(blocks))
+	}
+	if blocks[3].Pos.Line != 6 {
+		t.Errorf("blocks[3].Pos.Line = %d; want 6", blocks[3].Pos.Line)
+	}
+
+	lines := Lines(blocks)
+	exp := []string{"G21G90", "G0X1Y2", "G1Z-1F100"}
+	if len(lines) != len(exp) {
+		t.Fatalf("len(lines) = %d; want %d", len(lines), len(exp))
+	}
+	for i, l := range lines {
+		if l.String() != exp[i] {
+			t.Errorf("lines[%d] = %s; want %s", i, l.String(), exp[i])
+		}
+	}
+
+	_, err = Parse(bytes.NewBufferString("G0 X1\nG0 X?\n"))
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("err = %v; want %T", err, &SyntaxError{})
+	}
+	if serr.Pos.Line != 2 || serr.Pos.Col != 5 {
+		t.Errorf("Pos = %d:%d; want 2:5", serr.Pos.Line, serr.Pos.Col)
+	}
+}
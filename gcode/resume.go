@@ -0,0 +1,163 @@
+package gcode
+
+import "fmt"
+
+// SpindleDelay is the dwell, in seconds, to allow the spindle to reach speed when resuming.
+const SpindleDelay = 3
+
+// Resume will return the lines required to continue a program at lines[start], from a machine
+// that was reset (e.g. after an alarm or power loss) with its work zero intact.
+//
+// The modal state before lines[start] is restored, the tool is retracted to the highest Z of
+// the program, moved over the start position, and brought down at the active feed rate.
+// The returned lines end with lines[start], with the arc mode (G2, G3) added if it continues
+// one, and should be followed by lines[start+1:].
+func Resume(lines []Line, start int) ([]Line, error) {
+	if start <= 0 {
+		return nil, nil
+	}
+	if start >= len(lines) {
+		return nil, fmt.Errorf("start line %d is past the end of the program", start+1)
+	}
+	states, err := Interpret(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	// find a safe height in the coordinate system without G92 applied
+	safeZ := states[0].Position[2] + states[0].G92[2]
+	for _, s := range states {
+		if z := s.Position[2] + s.G92[2]; z > safeZ {
+			safeZ = z
+		}
+	}
+
+	s := states[start-1]
+	pos := [3]float64{
+		s.Position[0] + s.G92[0],
+		s.Position[1] + s.G92[1],
+		s.Position[2] + s.G92[2],
+	}
+
+	// positioning is always done in mm and absolute coordinates
+	res := []Line{
+		{{Type: 'G', Value: 92.1}},
+		{{Type: 'G', Value: 21}, {Type: 'G', Value: 90}, {Type: 'G', Value: 94}, {Type: 'G', Value: 17}, {Type: 'G', Value: float64(s.WCS)}},
+	}
+	if s.ToolLengthOffset != 0 {
+		res = append(res, Line{{Type: 'G', Value: 43.1}, {Type: 'Z', Value: s.ToolLengthOffset}})
+	} else {
+		res = append(res, Line{{Type: 'G', Value: 49}})
+	}
+	res = append(res, Line{{Type: 'G', Value: 0}, {Type: 'Z', Value: safeZ}})
+
+	if s.Tool != 0 {
+		res = append(res, Line{{Type: 'T', Value: float64(s.Tool)}})
+	}
+	res = append(res, spindle(s)...)
+
+	res = append(res, Line{{Type: 'G', Value: 0}, {Type: 'X', Value: pos[0]}, {Type: 'Y', Value: pos[1]}})
+	if s.FeedMode == FeedUnitsPerMinute && s.Feed > 0 {
+		res = append(res, Line{{Type: 'G', Value: 1}, {Type: 'Z', Value: pos[2]}, {Type: 'F', Value: s.Feed}})
+	} else {
+		res = append(res, Line{{Type: 'G', Value: 0}, {Type: 'Z', Value: pos[2]}})
+	}
+
+	if s.G92 != [3]float64{} {
+		res = append(res, Line{{Type: 'G', Value: 92}, {Type: 'X', Value: s.Position[0]}, {Type: 'Y', Value: s.Position[1]}, {Type: 'Z', Value: s.Position[2]}})
+	}
+
+	// restore the remaining modes last, as they change how positions are interpreted
+	if m := modes(s); len(m) > 0 {
+		res = append(res, m)
+	}
+
+	return append(res, withMotion(lines[start], s)), nil
+}
+
+// withMotion returns l with the motion mode of s added, if l continues an arc without its own motion
+// command. Arcs can't be restored by modes, as G2 and G3 require axis words.
+func withMotion(l Line, s State) Line {
+	if (s.Motion != MotionCW && s.Motion != MotionCCW) || !isMove(l) {
+		return l
+	}
+	for _, w := range l {
+		if w.Type == 'G' {
+			switch w.Value {
+			case 0, 1, 2, 3, 38.2, 38.3, 38.4, 38.5, 80:
+				return l
+			}
+		}
+	}
+
+	// keep the line number first
+	res := make(Line, 0, len(l)+1)
+	if len(l) > 0 && l[0].Type == 'N' {
+		res = append(res, l[0])
+		l = l[1:]
+	}
+	res = append(res, Word{Type: 'G', Value: s.Motion})
+	return append(res, l...)
+}
+
+// spindle returns the lines to restore the spindle and coolant state, waiting for the spindle
+// to reach speed if it is on.
+func spindle(s State) []Line {
+	var res []Line
+	switch s.Spindle {
+	case SpindleCW:
+		res = append(res, Line{{Type: 'M', Value: 3}, {Type: 'S', Value: s.SpindleSpeed}})
+	case SpindleCCW:
+		res = append(res, Line{{Type: 'M', Value: 4}, {Type: 'S', Value: s.SpindleSpeed}})
+	default:
+		res = append(res, Line{{Type: 'M', Value: 5}, {Type: 'S', Value: s.SpindleSpeed}})
+	}
+	if s.Mist {
+		res = append(res, Line{{Type: 'M', Value: 7}})
+	}
+	if s.Flood {
+		res = append(res, Line{{Type: 'M', Value: 8}})
+	}
+	if !s.Mist && !s.Flood {
+		res = append(res, Line{{Type: 'M', Value: 9}})
+	}
+	if s.Spindle != SpindleOff {
+		res = append(res, Line{{Type: 'G', Value: 4}, {Type: 'P', Value: SpindleDelay}})
+	}
+
+	return res
+}
+
+// modes returns a line restoring the plane, units, distance and feed modes, along with the motion
+// mode and feed rate, from G17, G21, G90 and G94.
+func modes(s State) Line {
+	var l Line
+	switch s.Plane {
+	case PlaneZX:
+		l = append(l, Word{Type: 'G', Value: 18})
+	case PlaneYZ:
+		l = append(l, Word{Type: 'G', Value: 19})
+	}
+	if s.Units == UnitsInches {
+		l = append(l, Word{Type: 'G', Value: 20})
+	}
+	if s.Distance == DistanceIncremental {
+		l = append(l, Word{Type: 'G', Value: 91})
+	}
+	if s.FeedMode == FeedInverseTime {
+		l = append(l, Word{Type: 'G', Value: 93})
+	}
+	switch s.Motion {
+	case MotionRapid, MotionLinear, MotionCancel:
+		// arcs and probing can't be selected without axis words
+		l = append(l, Word{Type: 'G', Value: s.Motion})
+	}
+	if s.FeedMode == FeedUnitsPerMinute && s.Feed > 0 {
+		f := s.Feed
+		if s.Units == UnitsInches {
+			f /= 25.4
+		}
+		l = append(l, Word{Type: 'F', Value: f})
+	}
+	return l
+}
@@ -0,0 +1,62 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResume(t *testing.T) {
+	lines := parseLines(t, `
+G21 G90 G55
+G0 Z5
+M3 S12000 M8
+G0 X10 Y5
+G1 Z-1 F300
+G20 G91 X1
+X1
+`)
+	res, err := Resume(lines, 6)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	s := make([]string, len(res))
+	for i, l := range res {
+		s[i] = l.String()
+	}
+	exp := []string{
+		"G92.1",
+		"G21G90G94G17G55",
+		"G49",
+		"G0Z5",
+		"M3S12000",
+		"M8",
+		"G4P3",
+		"G0X35.4Y5",
+		"G1Z-1F300",
+		"G20G91G1F11.811",
+		"X1",
+	}
+	if strings.Join(s, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(s, "\n"), strings.Join(exp, "\n"))
+	}
+
+	// a modal arc must be selected again, as G2 and G3 can't be restored without axis words
+	lines = parseLines(t, "G2 X10 Y0 I5 J0 F100\nX0 Y0 I-5 J0")
+	lines[1] = append(Line{{Type: 'N', Value: 2}}, lines[1]...)
+	res, err = Resume(lines, 1)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if l := res[len(res)-1].String(); l != "N2G2X0Y0I-5J0" {
+		t.Errorf("first resumed line = %s; want N2G2X0Y0I-5J0", l)
+	}
+
+	res, err = Resume(lines, 0)
+	if err != nil || res != nil {
+		t.Errorf("Resume(0) = %v, %v; want nil, nil", res, err)
+	}
+	_, err = Resume(lines, len(lines))
+	if err == nil {
+		t.Error("Resume(past end) err = nil; want error")
+	}
+}
@@ -0,0 +1,67 @@
+package gcode
+
+import "fmt"
+
+// ToolChanges returns the index of each line with a tool change (M6).
+func ToolChanges(lines []Line) []int {
+	var res []int
+	for i, l := range lines {
+		for _, w := range l {
+			if w.Type == 'M' && w.Value == 6 {
+				res = append(res, i)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// ToolChangeResume will return the lines required to continue a program at lines[start] after a
+// manual tool change, where the spindle and coolant were stopped and the tool retracted.
+//
+// The tool is moved over the last position and brought down at the active feed rate, then the spindle
+// and modal state before lines[start] are restored. Axes the program has not positioned yet are left
+// where they are (i.e. at the tool change clearance height). Unlike Resume, offsets (G92 and tool length)
+// are left as they are on the machine. The returned lines should be followed by lines[start:].
+func ToolChangeResume(lines []Line, start int) ([]Line, error) {
+	if start >= len(lines) {
+		return nil, fmt.Errorf("start line %d is past the end of the program", start+1)
+	}
+	if start <= 0 {
+		return nil, nil
+	}
+	states, err := Interpret(lines[:start])
+	if err != nil {
+		return nil, err
+	}
+	s := states[start-1]
+	var known [3]bool
+	for i, l := range lines[:start] {
+		updateKnown(&known, l, states[i])
+	}
+
+	// positioning is always done in mm and absolute coordinates
+	res := []Line{{{Type: 'G', Value: 21}, {Type: 'G', Value: 90}, {Type: 'G', Value: 94}, {Type: 'G', Value: 17}}}
+	res = append(res, spindle(s)...)
+	xy := Line{{Type: 'G', Value: 0}}
+	for i, w := range []byte{'X', 'Y'} {
+		if known[i] {
+			xy = append(xy, Word{Type: w, Value: s.Position[i]})
+		}
+	}
+	if len(xy) > 1 {
+		res = append(res, xy)
+	}
+	switch {
+	case !known[2]:
+	case s.FeedMode == FeedUnitsPerMinute && s.Feed > 0:
+		res = append(res, Line{{Type: 'G', Value: 1}, {Type: 'Z', Value: s.Position[2]}, {Type: 'F', Value: s.Feed}})
+	default:
+		res = append(res, Line{{Type: 'G', Value: 0}, {Type: 'Z', Value: s.Position[2]}})
+	}
+	if m := modes(s); len(m) > 0 {
+		res = append(res, m)
+	}
+
+	return res, nil
+}
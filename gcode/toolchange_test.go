@@ -0,0 +1,62 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolChangeResume(t *testing.T) {
+	lines := parseLines(t, `
+G20 G90
+T1 M6
+M3 S10000
+G0 X1 Y2
+G1 Z-0.1 F10
+M5
+T2 M6
+M3 S12000
+`)
+	changes := ToolChanges(lines)
+	if len(changes) != 2 || changes[0] != 1 || changes[1] != 6 {
+		t.Fatalf("ToolChanges = %v; want [1 6]", changes)
+	}
+
+	res, err := ToolChangeResume(lines, 6)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	s := make([]string, len(res))
+	for i, l := range res {
+		s[i] = l.String()
+	}
+	exp := []string{
+		"G21G90G94G17",
+		"M5S10000",
+		"M9",
+		"G0X25.4Y50.8",
+		"G1Z-2.54F254",
+		"G20G1F10",
+	}
+	if strings.Join(s, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(s, "\n"), strings.Join(exp, "\n"))
+	}
+
+	// nothing positioned before the first tool change, so the tool stays retracted
+	res, err = ToolChangeResume(lines, 1)
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	s = s[:0]
+	for _, l := range res {
+		s = append(s, l.String())
+	}
+	exp = []string{
+		"G21G90G94G17",
+		"M5S0",
+		"M9",
+		"G20G0",
+	}
+	if strings.Join(s, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(s, "\n"), strings.Join(exp, "\n"))
+	}
+}
@@ -0,0 +1,81 @@
+package grbl
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// DefaultRXBufferSize is the size of Grbl's serial receive buffer, in bytes, used until the
+// actual size is known.
+const DefaultRXBufferSize = 128
+
+// Buffers is the size and use of Grbl's planner and serial receive buffers.
+type Buffers struct {
+	// PlannerBlocks and RXBytes are the sizes of the buffers, from the build info ($I)
+	// or the first idle status report.
+	PlannerBlocks int
+	RXBytes       int
+
+	// PlannerUsed and RXUsed are from the last status report with buffer data ($10).
+	PlannerUsed int
+	RXUsed      int
+
+	// Pending is the number of bytes sent to Grbl and not yet acknowledged, and Queued is the
+	// number of commands waiting to be sent.
+	Pending int
+	Queued  int
+}
+
+// parseBuildOptions will return the buffer sizes from the build options line of `$I`
+// (e.g. `[OPT:VL,15,128]`).
+func parseBuildOptions(data []byte) (planner, rx int, ok bool) {
+	if !bytes.HasPrefix(data, []byte("[OPT:")) || !bytes.HasSuffix(data, []byte("]")) {
+		return 0, 0, false
+	}
+	// other builds (e.g. grblHAL) may report more fields
+	parts := bytes.Split(data[5:len(data)-1], []byte(","))
+	if len(parts) < 3 {
+		return 0, 0, false
+	}
+	planner, err := strconv.Atoi(string(parts[1]))
+	if err != nil || planner <= 0 {
+		return 0, 0, false
+	}
+	rx, err = strconv.Atoi(string(parts[2]))
+	if err != nil || rx <= 0 {
+		return 0, 0, false
+	}
+	return planner, rx, true
+}
+
+// Buffers returns the size and use of Grbl's buffers, as of the last status report.
+func (g *Grbl) Buffers() Buffers {
+	var b Buffers
+	g.sync(func() { b = g.s.Buffers })
+	return b
+}
+
+// setBufferSizes will set the buffer sizes, and use the RX buffer size for character counting.
+func (g *Grbl) setBufferSizes(planner, rx int) {
+	g.s.Buffers.PlannerBlocks = planner
+	g.s.Buffers.RXBytes = rx
+	g.c.SetRXBufferSize(rx)
+}
+
+// mergeBuffers will update the buffer use from the status report s.
+func (g *Grbl) mergeBuffers(s *Status) {
+	b := &g.s.Buffers
+	if hasField(s.Fields, "Bf") {
+		g.s.BlockBufferAvailable = s.BlockBufferAvailable
+		g.s.SerialBufferAvailable = s.SerialBufferAvailable
+
+		// the planner is empty when idle, but commands may be in the RX buffer, so $I is preferred
+		if !g.buildInfo && !g.bfSizes && s.State == StateIdle {
+			g.bfSizes = true
+			g.setBufferSizes(s.BlockBufferAvailable, s.SerialBufferAvailable)
+		}
+		b.PlannerUsed = b.PlannerBlocks - s.BlockBufferAvailable
+		b.RXUsed = b.RXBytes - s.SerialBufferAvailable
+	}
+	b.Pending, b.Queued = g.c.Pending()
+}
@@ -0,0 +1,47 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/gg/grbl/sim"
+)
+
+func TestParseBuildOptions(t *testing.T) {
+	for _, tc := range []struct {
+		data        string
+		planner, rx int
+		ok          bool
+	}{
+		{"[OPT:VL,15,128]", 15, 128, true},
+		{"[OPT:VNMSL,35,1024,3,0]", 35, 1024, true},
+		{"[OPT:V]", 0, 0, false},
+		{"[VER:1.1h.20190825:]", 0, 0, false},
+	} {
+		planner, rx, ok := parseBuildOptions([]byte(tc.data))
+		if planner != tc.planner || rx != tc.rx || ok != tc.ok {
+			t.Errorf("%s: got %d, %d, %t; want %d, %d, %t", tc.data, planner, rx, ok, tc.planner, tc.rx, tc.ok)
+		}
+	}
+}
+
+func TestGrbl_Buffers(t *testing.T) {
+	m := sim.NewMachine()
+	defer m.Close()
+	g := NewGrbl(m)
+	<-g.Settings()
+	if err := responseErr(<-g.c.Execute([]byte("$10=2\n"))); err != nil {
+		t.Fatal(err)
+	}
+
+	s := <-g.Status()
+	b := s.Buffers
+	if b.PlannerBlocks != sim.PlannerSize || b.RXBytes != sim.RXBufferSize {
+		t.Errorf("sizes = %d, %d; want %d, %d", b.PlannerBlocks, b.RXBytes, sim.PlannerSize, sim.RXBufferSize)
+	}
+	if b.PlannerUsed != 0 || b.RXUsed != 0 || b.Pending != 0 || b.Queued != 0 {
+		t.Errorf("buffers = %+v; want empty", b)
+	}
+	if n := g.Buffers().PlannerBlocks; n != sim.PlannerSize {
+		t.Errorf("Buffers().PlannerBlocks = %d; want %d", n, sim.PlannerSize)
+	}
+}
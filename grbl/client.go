@@ -3,12 +3,11 @@ package grbl
 import (
 	"bufio"
 	"bytes"
-	"errors"
+	"context"
 	"io"
+	"sync/atomic"
 )
 
-const grblBufSize = 50
-
 type ClientMode int
 
 const (
@@ -17,6 +16,11 @@ const (
 )
 
 type Client struct {
+	// rxSize is the size of Grbl's serial receive buffer, and pending and queued are the bytes
+	// waiting for a response and commands waiting to be sent. gen is incremented each time the
+	// connection is lost. They are accessed atomically, so are kept first for alignment.
+	rxSize, pending, queued, gen int64
+
 	rwc  io.ReadWriteCloser
 	mode ClientMode
 
@@ -28,13 +32,28 @@ type Client struct {
 
 	sendCh chan *clientRequest
 
+	// cancelCh is signaled when a context is done, to discard any cancelled commands not yet sent
+	cancelCh chan struct{}
+
 	err error
 }
 
 type clientRequest struct {
 	data  []byte
 	resCh chan *Response
+
+	// gen is the connection the request was made on
+	gen int64
+
+	// ctx is set for requests made with a context
+	ctx context.Context
 }
+
+// cancelled returns true if the request was made with a context that is done.
+func (req *clientRequest) cancelled() bool {
+	return req.ctx != nil && req.ctx.Err() != nil
+}
+
 type Response struct {
 	Data []byte
 	Err  error
@@ -44,12 +63,14 @@ func NewClient(rwc io.ReadWriteCloser, mode ClientMode) *Client {
 	c := &Client{
 		rwc:        rwc,
 		mode:       mode,
+		rxSize:     DefaultRXBufferSize,
 		getMode:    make(chan ClientMode),
 		setMode:    make(chan ClientMode),
 		closeCh:    make(chan struct{}),
 		pushCh:     make(chan []byte, 100),
 		responseCh: make(chan []byte, 10000),
 		sendCh:     make(chan *clientRequest, 10000),
+		cancelCh:   make(chan struct{}, 1),
 		ioErrCh:    make(chan error, 10),
 	}
 
@@ -73,6 +94,14 @@ func (c *Client) readLoop() {
 	var push byte
 	for {
 		b, err = r.ReadByte()
+		if err == ErrConnectionLost {
+			// the Transport will reconnect, so discard any partial line
+			c.ioErrCh <- err
+			buf = buf[:0]
+			pushBuf = pushBuf[:0]
+			push = 0
+			continue
+		}
 		if err != nil {
 			c.ioErrCh <- err
 			return
@@ -120,12 +149,26 @@ func (c *Client) writeLoop() {
 	var req *clientRequest
 	var data []byte
 
+	// resBuf has an entry for each command sent (grblBuf) followed by
+	// each command waiting to be sent (sendBuf).
 	var grblBuf []int
-	var sendBuf [][]byte
+	var sendBuf []*clientRequest
 	var resBuf []chan *Response
+	var alarm Alarm
+
+	// skip will discard cancelled commands at the front of sendBuf, as the context
+	// may be done before the cancel signal is handled.
+	skip := func() {
+		for len(sendBuf) > 0 && sendBuf[0].cancelled() {
+			sendBuf[0].resCh <- &Response{Err: sendBuf[0].ctx.Err()}
+			sendBuf[0] = nil
+			sendBuf = sendBuf[1:]
+			resBuf = append(resBuf[:len(grblBuf)], resBuf[len(grblBuf)+1:]...)
+		}
+	}
 
 	sendOne := func() (n int) {
-		n, c.err = c.rwc.Write(sendBuf[0])
+		n, c.err = c.rwc.Write(sendBuf[0].data)
 		if c.err != nil {
 			return n
 		}
@@ -134,7 +177,41 @@ func (c *Client) writeLoop() {
 		return n
 	}
 
+	// discard will respond to, and remove, any cancelled commands that have not been sent.
+	discard := func() {
+		keep := sendBuf[:0]
+		res := resBuf[:len(grblBuf)]
+		for i, req := range sendBuf {
+			if req.cancelled() {
+				req.resCh <- &Response{Err: req.ctx.Err()}
+				continue
+			}
+			keep = append(keep, req)
+			res = append(res, resBuf[len(grblBuf)+i])
+		}
+		for i := len(keep); i < len(sendBuf); i++ {
+			sendBuf[i] = nil
+		}
+		sendBuf = keep
+		resBuf = res
+	}
+
+	// lost will fail all requests after the connection is lost, as Grbl may not have
+	// received them (or may be reset on reconnect).
+	lost := func() {
+		atomic.AddInt64(&c.gen, 1)
+		for _, r := range resBuf {
+			r <- &Response{Err: c.err}
+		}
+		grblBuf = grblBuf[:0]
+		sendBuf = sendBuf[:0]
+		resBuf = resBuf[:0]
+		alarm = 0
+		c.err = nil
+	}
+
 	fillGrbl := func() {
+		skip()
 		if len(sendBuf) == 0 {
 			return
 		}
@@ -150,8 +227,11 @@ func (c *Client) writeLoop() {
 		for _, n := range grblBuf {
 			s += n
 		}
-		for c.err == nil && len(sendBuf) > 0 && s+len(sendBuf[0]) <= grblBufSize {
+		// Grbl's ring buffer holds one less than its size
+		max := int(atomic.LoadInt64(&c.rxSize)) - 1
+		for c.err == nil && len(sendBuf) > 0 && s+len(sendBuf[0].data) <= max {
 			s += sendOne()
+			skip()
 		}
 	}
 
@@ -161,20 +241,28 @@ func (c *Client) writeLoop() {
 		case c.mode = <-c.setMode:
 		case <-c.closeCh:
 			return
+		case <-c.cancelCh:
+			discard()
 		case req = <-c.sendCh:
+			if req.gen != atomic.LoadInt64(&c.gen) {
+				req.resCh <- &Response{Err: ErrConnectionLost}
+				continue
+			}
+			if req.cancelled() {
+				req.resCh <- &Response{Err: req.ctx.Err()}
+				continue
+			}
 			if isRealtimeCommand(req.data) {
 				// write immediately, and respond after
 				_, c.err = c.rwc.Write(req.data)
-				if c.err != nil {
-					req.resCh <- &Response{Err: c.err}
-					c.errMode()
-					return
+				req.resCh <- &Response{Err: c.err}
+				if c.err == nil {
+					continue
 				}
-				req.resCh <- &Response{}
-				continue
+				break
 			}
 
-			sendBuf = append(sendBuf, req.data)
+			sendBuf = append(sendBuf, req)
 			resBuf = append(resBuf, req.resCh)
 			fillGrbl()
 		case data = <-c.responseCh:
@@ -184,12 +272,22 @@ func (c *Client) writeLoop() {
 				resBuf = resBuf[1:]
 				fillGrbl()
 			} else { //push messages
+				if a, ok := parseAlarm(data); ok {
+					alarm = a
+				}
 				if bytes.HasPrefix(data, []byte("Grbl")) {
-					for i := 0; i < len(grblBuf); i++ {
-						resBuf[i] <- &Response{Err: errors.New("soft reset")}
+					// an alarm (e.g. soft limit) causes a reset
+					var err error = ErrSoftReset
+					if alarm != 0 {
+						err = alarm
+					}
+					// including those not sent, so nothing waits forever
+					for _, r := range resBuf {
+						r <- &Response{Err: err}
 					}
 					grblBuf = grblBuf[:0]
 					resBuf = resBuf[:0]
+					alarm = 0
 
 					// don't send any commands that were pending pre-reset
 					sendBuf = sendBuf[:0]
@@ -197,15 +295,27 @@ func (c *Client) writeLoop() {
 				c.pushCh <- data
 			}
 		case c.err = <-c.ioErrCh:
-			for _, r := range resBuf {
-				r <- &Response{Err: c.err}
+			if c.err != ErrConnectionLost {
+				for _, r := range resBuf {
+					r <- &Response{Err: c.err}
+				}
+				resBuf = resBuf[:0]
 			}
-			resBuf = resBuf[:0]
+		}
+		if c.err == ErrConnectionLost {
+			lost()
 		}
 		if c.err != nil {
 			c.errMode()
 			return
 		}
+
+		var s int
+		for _, n := range grblBuf {
+			s += n
+		}
+		atomic.StoreInt64(&c.pending, int64(s))
+		atomic.StoreInt64(&c.queued, int64(len(sendBuf)))
 	}
 }
 
@@ -230,20 +340,67 @@ func (c *Client) SetMode(m ClientMode) {
 	c.setMode <- m
 }
 
+// SetRXBufferSize will set the size of Grbl's serial receive buffer, in bytes, used for character counting.
+// It takes effect for the next command sent.
+func (c *Client) SetRXBufferSize(n int) {
+	atomic.StoreInt64(&c.rxSize, int64(n))
+}
+
+// Pending returns the number of bytes sent and waiting for a response, and the number of
+// commands waiting to be sent.
+func (c *Client) Pending() (sent, waiting int) {
+	return int(atomic.LoadInt64(&c.pending)), int(atomic.LoadInt64(&c.queued))
+}
+
 func (c *Client) Execute(command []byte) chan *Response {
 	ch := make(chan *Response, 1)
 	c.sendCh <- &clientRequest{
 		data:  command,
 		resCh: ch,
+		gen:   atomic.LoadInt64(&c.gen),
 	}
 
 	return ch
 }
+
+// cancel will discard any commands not yet sent whose context is done.
+func (c *Client) cancel() {
+	select {
+	case c.cancelCh <- struct{}{}:
+	default:
+	}
+}
+
+// ExecuteContext will send command and wait for the response. If ctx is done first, the command
+// is discarded (if not yet sent) and ctx.Err() is returned. Errors are returned as a *CommandError.
+func (c *Client) ExecuteContext(ctx context.Context, command []byte) error {
+	req := &clientRequest{
+		data:  command,
+		resCh: make(chan *Response, 1),
+		gen:   atomic.LoadInt64(&c.gen),
+		ctx:   ctx,
+	}
+	select {
+	case c.sendCh <- req:
+	case <-ctx.Done():
+		return commandErr(command, ctx.Err())
+	}
+
+	select {
+	case r := <-req.resCh:
+		return commandErr(command, responseErr(r))
+	case <-ctx.Done():
+		c.cancel()
+		return commandErr(command, ctx.Err())
+	}
+}
+
 func (c *Client) ExecuteMany(commands [][]byte) chan *Response {
 	resCh := make(chan *Response, len(commands))
 	ch := make(chan *Response, len(commands))
+	gen := atomic.LoadInt64(&c.gen)
 	for _, data := range commands {
-		c.sendCh <- &clientRequest{data: data, resCh: ch}
+		c.sendCh <- &clientRequest{data: data, resCh: ch, gen: gen}
 	}
 
 	go func() {
@@ -257,6 +414,45 @@ func (c *Client) ExecuteMany(commands [][]byte) chan *Response {
 	return resCh
 }
 
+// ExecuteManyContext is like ExecuteMany, but when ctx is done any commands not yet sent are discarded,
+// and a response with ctx.Err() is returned for each command that has not responded.
+func (c *Client) ExecuteManyContext(ctx context.Context, commands [][]byte) chan *Response {
+	resCh := make(chan *Response, len(commands))
+	ch := make(chan *Response, len(commands))
+	gen := atomic.LoadInt64(&c.gen)
+
+	go func() {
+		defer close(resCh)
+		var queued int
+	send:
+		for _, data := range commands {
+			select {
+			case c.sendCh <- &clientRequest{data: data, resCh: ch, gen: gen, ctx: ctx}:
+				queued++
+			case <-ctx.Done():
+				break send
+			}
+		}
+
+		var n int
+	recv:
+		for ; n < queued; n++ {
+			select {
+			case r := <-ch:
+				resCh <- r
+			case <-ctx.Done():
+				c.cancel()
+				break recv
+			}
+		}
+		for ; n < len(commands); n++ {
+			resCh <- &Response{Err: ctx.Err()}
+		}
+	}()
+
+	return resCh
+}
+
 func (c *Client) PushMessages() chan []byte {
 	return c.pushCh
 }
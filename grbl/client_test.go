@@ -0,0 +1,70 @@
+package grbl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestClient_ExecuteContext(t *testing.T) {
+	conn := newFakeConn()
+	c := NewClient(conn, ModeSendResponse)
+	defer c.Close()
+
+	ok := c.Execute([]byte("G0X1\n"))
+	if l, _ := conn.in.ReadString('\n'); l != "G0X1\n" {
+		t.Fatalf("sent %q; want G0X1", l)
+	}
+
+	// waits behind G0X1, so is never sent
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.ExecuteContext(ctx, []byte("G0X2\n"))
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Command != "G0X2" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v; want G0X2 deadline exceeded", err)
+	}
+
+	next := c.Execute([]byte("G0X3\n"))
+	io.WriteString(conn.out, "ok\r\n")
+	if r := <-ok; r.Err != nil {
+		t.Errorf("G0X1 err = %v; want nil", r.Err)
+	}
+	if l, _ := conn.in.ReadString('\n'); l != "G0X3\n" {
+		t.Fatalf("sent %q; want G0X3", l)
+	}
+	io.WriteString(conn.out, "error:20\r\n")
+	if err := responseErr(<-next); err != Error(20) {
+		t.Errorf("G0X3 err = %v; want error:20", err)
+	}
+}
+
+func TestClient_ExecuteManyContext(t *testing.T) {
+	conn := newFakeConn()
+	c := NewClient(conn, ModeSendResponse)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp := c.ExecuteManyContext(ctx, [][]byte{[]byte("G0X1\n"), []byte("G0X2\n"), []byte("G0X3\n")})
+	if l, _ := conn.in.ReadString('\n'); l != "G0X1\n" {
+		t.Fatalf("sent %q; want G0X1", l)
+	}
+	io.WriteString(conn.out, "ok\r\n")
+	if r := <-resp; r.Err != nil {
+		t.Errorf("G0X1 err = %v; want nil", r.Err)
+	}
+	cancel()
+
+	var n int
+	for r := range resp {
+		n++
+		if r.Err != context.Canceled {
+			t.Errorf("err = %v; want canceled", r.Err)
+		}
+	}
+	if n != 2 {
+		t.Errorf("got %d responses after cancel; want 2", n)
+	}
+}
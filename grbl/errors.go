@@ -0,0 +1,163 @@
+package grbl
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// ErrSoftReset is returned for commands that were pending when Grbl was reset.
+var ErrSoftReset = errors.New("soft reset")
+
+// CommandError is returned by the context variants (e.g. ExecuteContext) when a command fails. Err
+// is the cause, such as an Error from Grbl, ErrSoftReset, ErrConnectionLost or context.Canceled.
+type CommandError struct {
+	Command string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	return "command " + strconv.Quote(e.Command) + ": " + e.Err.Error()
+}
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// commandErr returns err, if not nil, as a *CommandError for cmd.
+func commandErr(cmd []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &CommandError{Command: string(bytes.TrimSpace(cmd)), Err: err}
+}
+
+// Error is an error code returned by Grbl in response to a command (e.g. `error:20`).
+type Error int
+
+// Alarm is an alarm code sent by Grbl when it enters the alarm state (e.g. `ALARM:2`).
+type Alarm int
+
+// Alarm codes
+const (
+	AlarmProbeInitial Alarm = 4
+	AlarmProbeContact Alarm = 5
+)
+
+var errorDescriptions = map[Error]string{
+	1:  "G-code words consist of a letter and a value. Letter was not found.",
+	2:  "Numeric value format is not valid or missing an expected value.",
+	3:  "Grbl '$' system command was not recognized or supported.",
+	4:  "Negative value received for an expected positive value.",
+	5:  "Homing cycle is not enabled via settings.",
+	6:  "Minimum step pulse time must be greater than 3usec.",
+	7:  "EEPROM read failed. Reset and restored to default values.",
+	8:  "Grbl '$' command cannot be used unless Grbl is IDLE. Ensures smooth operation during a job.",
+	9:  "G-code locked out during alarm or jog state.",
+	10: "Soft limits cannot be enabled without homing also enabled.",
+	11: "Max characters per line exceeded. Line was not processed and executed.",
+	12: "Grbl '$' setting value exceeds the maximum step rate supported.",
+	13: "Safety door detected as opened and door state initiated.",
+	14: "Build info or startup line exceeded EEPROM line length limit.",
+	15: "Jog target exceeds machine travel. Command ignored.",
+	16: "Jog command with no '=' or contains prohibited g-code.",
+	17: "Laser mode requires PWM output.",
+	20: "Unsupported or invalid g-code command found in block.",
+	21: "More than one g-code command from same modal group found in block.",
+	22: "Feed rate has not yet been set or is undefined.",
+	23: "G-code command in block requires an integer value.",
+	24: "Two G-code commands that both require the use of the XYZ axis words were detected in the block.",
+	25: "A G-code word was repeated in the block.",
+	26: "A G-code command implicitly or explicitly requires XYZ axis words in the block, but none were detected.",
+	27: "N line number value is not within the valid range of 1 - 9,999,999.",
+	28: "A G-code command was sent, but is missing some required P or L value words in the line.",
+	29: "Grbl supports six work coordinate systems G54-G59. G59.1, G59.2, and G59.3 are not supported.",
+	30: "The G53 G-code command requires either a G0 seek or G1 feed motion mode to be active.",
+	31: "There are unused axis words in the block and G80 motion mode cancel is active.",
+	32: "A G2 or G3 arc was commanded but there are no XYZ axis words in the selected plane to trace the arc.",
+	33: "The motion command has an invalid target.",
+	34: "A G2 or G3 arc, traced with the radius definition, had a mathematical error when computing the arc geometry.",
+	35: "A G2 or G3 arc, traced with the offset definition, is missing the IJK offset word in the selected plane to trace the arc.",
+	36: "There are unused, leftover G-code words that aren't used by any command in the block.",
+	37: "The G43.1 dynamic tool length offset command cannot apply an offset to an axis other than its configured axis.",
+	38: "Tool number greater than max supported value.",
+}
+
+var alarmDescriptions = map[Alarm]string{
+	1:  "Hard limit triggered. Machine position is likely lost due to sudden and immediate halt. Re-homing is highly recommended.",
+	2:  "G-code motion target exceeds machine travel. Machine position safely retained. Alarm may be unlocked.",
+	3:  "Reset while in motion. Grbl cannot guarantee position. Lost steps are likely. Re-homing is highly recommended.",
+	4:  "Probe fail. The probe is not in the expected initial state before starting probe cycle.",
+	5:  "Probe fail. Probe did not contact the workpiece within the programmed travel.",
+	6:  "Homing fail. Reset during active homing cycle.",
+	7:  "Homing fail. Safety door was opened during active homing cycle.",
+	8:  "Homing fail. Cycle failed to clear limit switch when pulling off.",
+	9:  "Homing fail. Could not find limit switch within search distance.",
+	10: "Homing fail. On dual axis machines, could not find the second limit switch for self-squaring.",
+}
+
+// Description returns a human-readable description of the error code.
+func (e Error) Description() string {
+	if d, ok := errorDescriptions[e]; ok {
+		return d
+	}
+	return "Unknown error."
+}
+
+func (e Error) Error() string {
+	return "grbl error " + strconv.Itoa(int(e)) + ": " + e.Description()
+}
+
+// Description returns a human-readable description of the alarm code.
+func (a Alarm) Description() string {
+	if d, ok := alarmDescriptions[a]; ok {
+		return d
+	}
+	return "Unknown alarm."
+}
+
+func (a Alarm) Error() string {
+	return "grbl alarm " + strconv.Itoa(int(a)) + ": " + a.Description()
+}
+
+// parseCode parses the numeric value from a message like `error:20` or `ALARM:1`.
+func parseCode(data []byte, prefix string) (int, bool) {
+	if !bytes.HasPrefix(data, []byte(prefix)) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(data[len(prefix):]))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func parseAlarm(data []byte) (Alarm, bool) {
+	n, ok := parseCode(data, "ALARM:")
+	return Alarm(n), ok
+}
+
+// responseErr returns the error (if any) for a command response.
+func responseErr(r *Response) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if len(r.Data) == 0 || r.Data[0] != 'e' {
+		return nil
+	}
+	if n, ok := parseCode(r.Data, "error:"); ok {
+		return Error(n)
+	}
+	return errors.New(string(r.Data))
+}
+
+// ErrorDescription returns a short description of err, suitable for display next to the
+// command that caused it.
+func ErrorDescription(err error) string {
+	var e Error
+	if errors.As(err, &e) {
+		return "error:" + strconv.Itoa(int(e)) + " " + e.Description()
+	}
+	var a Alarm
+	if errors.As(err, &a) {
+		return "ALARM:" + strconv.Itoa(int(a)) + " " + a.Description()
+	}
+	return err.Error()
+}
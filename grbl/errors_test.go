@@ -0,0 +1,76 @@
+package grbl
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl/sim"
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestResponseErr(t *testing.T) {
+	check := func(data string, exp error) {
+		t.Run(data, func(t *testing.T) {
+			err := responseErr(&Response{Data: []byte(data)})
+			if err != exp {
+				t.Errorf("err = %v; want %v", err, exp)
+			}
+		})
+	}
+	check("ok", nil)
+	check("error:20", Error(20))
+	check("error:9", Error(9))
+
+	err := responseErr(&Response{Data: []byte("error:Bad number format")})
+	if err == nil || err.Error() != "error:Bad number format" {
+		t.Errorf("err = %v; want error:Bad number format", err)
+	}
+}
+
+func TestError_As(t *testing.T) {
+	err := pkgerrors.Wrap(Error(22), "line 5")
+	var e Error
+	if !errors.As(err, &e) {
+		t.Fatal("errors.As = false; want true")
+	}
+	if e != 22 {
+		t.Errorf("Error = %d; want 22", e)
+	}
+	if ErrorDescription(err) != "error:22 Feed rate has not yet been set or is undefined." {
+		t.Errorf("ErrorDescription = %s", ErrorDescription(err))
+	}
+	if Alarm(99).Description() != "Unknown alarm." {
+		t.Errorf("Description = %s; want Unknown alarm.", Alarm(99).Description())
+	}
+}
+
+func TestGrbl_CheckGCode(t *testing.T) {
+	m := sim.NewMachine()
+	defer m.Close()
+	g := NewGrbl(m)
+	// wait for startup
+	<-g.Settings()
+
+	lines := []gcode.Line{
+		{{Type: 'G', Value: 0}, {Type: 'X', Value: 1}},
+		{{Type: 'G', Value: 41}},
+		{{Type: 'G', Value: 1}, {Type: 'X', Value: 2}, {Type: 'F', Value: 100}},
+	}
+	var errs []error
+	for stat := range g.CheckGCode(lines) {
+		if stat.Line > len(lines) {
+			continue
+		}
+		errs = append(errs, stat.Err)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("got %d results; want 3", len(errs))
+	}
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("errs = %v; want [nil error:20 nil]", errs)
+	}
+	if errs[1] != Error(20) {
+		t.Errorf("errs[1] = %v; want %v", errs[1], Error(20))
+	}
+}
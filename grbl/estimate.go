@@ -0,0 +1,308 @@
+package grbl
+
+import (
+	"math"
+	"time"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+// minJunctionSpeed is the minimum planner junction speed (mm/min), matching Grbl's MINIMUM_JUNCTION_SPEED.
+const minJunctionSpeed = 0.0
+
+// Estimate holds the expected execution time of each line of a program.
+type Estimate []time.Duration
+
+// Total returns the expected duration of the entire program.
+func (e Estimate) Total() time.Duration {
+	var t time.Duration
+	for _, d := range e {
+		t += d
+	}
+	return t
+}
+
+// Remaining returns the expected duration of the program, starting with
+// the given line number. Line numbers start at 1 (as reported by the `Ln` status field).
+func (e Estimate) Remaining(line int) time.Duration {
+	if line < 1 {
+		line = 1
+	}
+	if line > len(e) {
+		return 0
+	}
+	return e[line-1:].Total()
+}
+
+type planBlock struct {
+	line int
+
+	length  float64    // mm
+	unit    [3]float64 // unit vector
+	nominal float64    // mm/min
+	accel   float64    // mm/min^2
+
+	maxEntry float64 // mm/min
+	entry    float64
+	exit     float64
+
+	dwell time.Duration
+	stop  bool // planner is synchronized (e.g. dwell) before this block
+}
+
+type axisLimits struct {
+	rate  [3]float64 // mm/min
+	accel [3]float64 // mm/min^2
+	jd    float64    // mm
+
+	arcTol float64 // mm
+}
+
+func newAxisLimits(s Settings) (l axisLimits, ok bool) {
+	rates := [3]Rate{s.MaxRate.X, s.MaxRate.Y, s.MaxRate.Z}
+	accels := [3]Accel{s.MaxAcceleration.X, s.MaxAcceleration.Y, s.MaxAcceleration.Z}
+	for i := range rates {
+		if rates[i] == (Rate{}) || accels[i] == (Accel{}) {
+			return l, false
+		}
+		l.rate[i] = rates[i].MillimetersPerMinute()
+		l.accel[i] = accels[i].MMSec2() * 3600
+		if l.rate[i] <= 0 || l.accel[i] <= 0 {
+			return l, false
+		}
+	}
+	l.jd = s.JunctionDeviation.Millimeters()
+	l.arcTol = s.ArcTolerance.Millimeters()
+	return l, true
+}
+
+// limit returns the lowest per-axis limit along the unit vector.
+func limit(max [3]float64, unit [3]float64) float64 {
+	v := math.Inf(1)
+	for i := range unit {
+		if unit[i] == 0 {
+			continue
+		}
+		v = math.Min(v, max[i]/math.Abs(unit[i]))
+	}
+	return v
+}
+
+// segments returns the end points of the linear moves needed to execute
+// the last line run by in, starting at prev.
+func (l axisLimits) segments(prev [3]float64, in *gcode.Interpreter) [][3]float64 {
+	if a := in.Arc(); a != nil {
+		return a.Points(l.arcTol)
+	}
+	next := in.State().Position
+	if prev == next {
+		return nil
+	}
+	return [][3]float64{next}
+}
+
+func isMotion(m float64) bool {
+	switch m {
+	case gcode.MotionRapid, gcode.MotionLinear, gcode.MotionCW, gcode.MotionCCW:
+		return true
+	}
+	return false
+}
+
+// setsAxes returns true if the axis words of l set an offset or use a stored position,
+// instead of moving along the current motion mode.
+func setsAxes(l gcode.Line) bool {
+	return hasG(l, 10) || hasG(l, 92) || hasG(l, 28) || hasG(l, 30) || hasG(l, 43.1)
+}
+
+// isSync returns true if l causes Grbl to wait for the planner buffer to empty.
+func isSync(l gcode.Line) bool {
+	for _, w := range l {
+		switch {
+		case w.Type == 'G' && w.Value == 4:
+			return true
+		case w.Type == 'M':
+			switch w.Value {
+			case 0, 1, 2, 3, 4, 5, 30:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (l axisLimits) plan(lines []gcode.Line) []planBlock {
+	var blocks []planBlock
+	in := gcode.NewInterpreter()
+	stop := true
+	for n, line := range lines {
+		prev := in.State()
+		err := in.Exec(line)
+		if err != nil {
+			// Grbl would reject it; nothing to plan
+			continue
+		}
+		s := in.State()
+		if isSync(line) {
+			stop = true
+		}
+		if hasG(line, 4) {
+			blocks = append(blocks, planBlock{line: n, dwell: time.Duration(line.Value('P') * float64(time.Second)), stop: true})
+			continue
+		}
+		if !isMotion(s.Motion) || setsAxes(line) {
+			continue
+		}
+
+		pts := l.segments(prev.Position, in)
+		if len(pts) == 0 {
+			continue
+		}
+		var total float64
+		start := prev.Position
+		for _, p := range pts {
+			total += dist(start, p)
+			start = p
+		}
+		start = prev.Position
+		for _, p := range pts {
+			b := planBlock{line: n, stop: stop}
+			stop = false
+			b.length = dist(start, p)
+			if b.length == 0 {
+				continue
+			}
+			for i := range b.unit {
+				b.unit[i] = (p[i] - start[i]) / b.length
+			}
+			start = p
+
+			maxRate := limit(l.rate, b.unit)
+			switch {
+			case s.Motion == gcode.MotionRapid:
+				b.nominal = maxRate
+			case s.FeedMode == gcode.FeedInverseTime:
+				// the entire line should complete in 1/F minutes
+				b.nominal = total * s.Feed
+			default:
+				b.nominal = s.Feed
+			}
+			b.nominal = math.Min(b.nominal, maxRate)
+			b.accel = limit(l.accel, b.unit)
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+func hasG(l gcode.Line, v float64) bool {
+	for _, w := range l {
+		if w.Type == 'G' && w.Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func dist(a, b [3]float64) float64 {
+	x, y, z := b[0]-a[0], b[1]-a[1], b[2]-a[2]
+	return math.Sqrt(x*x + y*y + z*z)
+}
+
+// junctionSpeed calculates the max speed through the junction of two blocks, using
+// the same junction deviation approximation as Grbl.
+func (l axisLimits) junctionSpeed(prev, next *planBlock) float64 {
+	cos := -(prev.unit[0]*next.unit[0] + prev.unit[1]*next.unit[1] + prev.unit[2]*next.unit[2])
+	if cos > 0.999999 {
+		// 180 degree reversal
+		return minJunctionSpeed
+	}
+	if cos < -0.999999 {
+		// straight line
+		return math.Inf(1)
+	}
+
+	var junction [3]float64
+	for i := range junction {
+		junction[i] = next.unit[i] - prev.unit[i]
+	}
+	n := math.Sqrt(junction[0]*junction[0] + junction[1]*junction[1] + junction[2]*junction[2])
+	for i := range junction {
+		junction[i] /= n
+	}
+	accel := limit(l.accel, junction)
+	sinHalf := math.Sqrt(0.5 * (1 - cos))
+	v2 := accel * l.jd * sinHalf / (1 - sinHalf)
+	return math.Max(minJunctionSpeed, math.Sqrt(v2))
+}
+
+// duration calculates the time required to execute a trapezoidal velocity profile.
+func (b *planBlock) duration() time.Duration {
+	if b.length == 0 {
+		return b.dwell
+	}
+	a := b.accel
+	vn := b.nominal
+	v0, v1 := b.entry, b.exit
+	accelDist := (vn*vn - v0*v0) / (2 * a)
+	decelDist := (vn*vn - v1*v1) / (2 * a)
+	var min float64
+	if accelDist+decelDist <= b.length {
+		min = (vn-v0)/a + (vn-v1)/a + (b.length-accelDist-decelDist)/vn
+	} else {
+		peak := math.Sqrt((2*a*b.length + v0*v0 + v1*v1) / 2)
+		min = (peak-v0)/a + (peak-v1)/a
+	}
+	return time.Duration(min * float64(time.Minute))
+}
+
+// EstimateGCode will calculate the expected execution time for each line of a program
+// using the acceleration and junction deviation planning done by Grbl.
+//
+// If the Settings do not contain max rates and acceleration (e.g. they have not been read
+// from the machine yet) the Estimate will be all zero.
+func EstimateGCode(s Settings, lines []gcode.Line) Estimate {
+	e := make(Estimate, len(lines))
+	l, ok := newAxisLimits(s)
+	if !ok {
+		return e
+	}
+	blocks := l.plan(lines)
+
+	// maximum entry speeds
+	for i := range blocks {
+		b := &blocks[i]
+		if b.stop || i == 0 || blocks[i-1].length == 0 || b.length == 0 {
+			continue
+		}
+		prev := &blocks[i-1]
+		b.maxEntry = math.Min(l.junctionSpeed(prev, b), math.Min(prev.nominal, b.nominal))
+	}
+
+	// reverse pass: ensure every block can decelerate to the next entry speed
+	next := 0.0
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := &blocks[i]
+		b.entry = math.Min(b.maxEntry, math.Sqrt(next*next+2*b.accel*b.length))
+		next = b.entry
+	}
+
+	// forward pass: ensure every block can accelerate to the next entry speed
+	for i := range blocks {
+		b := &blocks[i]
+		if i+1 < len(blocks) {
+			b.exit = blocks[i+1].entry
+		}
+		max := math.Sqrt(b.entry*b.entry + 2*b.accel*b.length)
+		if b.exit > max {
+			b.exit = max
+			blocks[i+1].entry = max
+		}
+	}
+
+	for i := range blocks {
+		e[blocks[i].line] += blocks[i].duration()
+	}
+
+	return e
+}
@@ -0,0 +1,86 @@
+package grbl
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+func testSettings() Settings {
+	var s Settings
+	rate := NewRate(Millimeter*1000, time.Minute)
+	accel := NewRate(Millimeter*10, time.Second).Accel(time.Second)
+	s.MaxRate.X, s.MaxRate.Y, s.MaxRate.Z = rate, rate, rate
+	s.MaxAcceleration.X, s.MaxAcceleration.Y, s.MaxAcceleration.Z = accel, accel, accel
+	s.JunctionDeviation = Micrometer * 10
+	s.ArcTolerance = Micrometer * 2
+	return s
+}
+
+func testLines(t *testing.T, data string) []gcode.Line {
+	blocks, err := gcode.Parse(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return gcode.Lines(blocks)
+}
+
+func TestEstimateGCode(t *testing.T) {
+	test := func(name, data string, exp time.Duration) {
+		t.Run(name, func(t *testing.T) {
+			e := EstimateGCode(testSettings(), testLines(t, data))
+			d := e.Total() - exp
+			if d < -time.Millisecond || d > time.Millisecond {
+				t.Errorf("Total() = %s; want %s", e.Total(), exp)
+			}
+		})
+	}
+
+	// 1s to accelerate/decelerate (5mm each), 90mm at 10mm/s
+	test("trapezoid", "G1 X100 F600", 11*time.Second)
+	// never reaches 10mm/s, 2mm to accelerate and decelerate at 10mm/s^2
+	test("triangle", "G1 X4 F600", 1264911064*time.Nanosecond)
+	// straight continuation does not slow down
+	test("straight", "G1 X50 F600\nX100", 11*time.Second)
+	// reversal must come to a full stop
+	test("reversal", "G1 X50 F600\nX0", 12*time.Second)
+	// rapids use max rate
+	test("rapid", "G0 X200", 13*time.Second+666666666*time.Nanosecond)
+	test("dwell", "G4 P2.5", 2500*time.Millisecond)
+	// 1mm/s nominal, plus 0.1s lost to acceleration
+	test("inverse time", "G93 G1 X10 F6", 10100*time.Millisecond)
+	// offsets and stored positions are not moves of the modal G1
+	test("non-motion axis words", "G1 X100 F600\nG92 X0\nG28 X0\nG10 L20 P0 X50", 11*time.Second)
+}
+
+func TestEstimateGCode_Arc(t *testing.T) {
+	// half circle of radius 50 at 10mm/s, plus 1s lost to acceleration
+	exp := (50*math.Pi/10 + 1) * float64(time.Second)
+	e := EstimateGCode(testSettings(), testLines(t, "G2 X100 I50 F600"))
+	if math.Abs(float64(e.Total())-exp) > float64(10*time.Millisecond) {
+		t.Errorf("Total() = %s; want %s", e.Total(), time.Duration(exp))
+	}
+}
+
+func TestEstimate_Remaining(t *testing.T) {
+	e := EstimateGCode(testSettings(), testLines(t, "G1 X50 F600\nG4 P1\nG1 X100"))
+	if e.Remaining(2) != e[1]+e[2] {
+		t.Errorf("Remaining(2) = %s; want %s", e.Remaining(2), e[1]+e[2])
+	}
+	if e.Remaining(0) != e.Total() {
+		t.Errorf("Remaining(0) = %s; want %s", e.Remaining(0), e.Total())
+	}
+	if e.Remaining(4) != 0 {
+		t.Errorf("Remaining(4) = %s; want 0", e.Remaining(4))
+	}
+}
+
+func TestEstimateGCode_NoSettings(t *testing.T) {
+	e := EstimateGCode(Settings{}, testLines(t, "G1 X100 F600"))
+	if e.Total() != 0 {
+		t.Errorf("Total() = %s; want 0", e.Total())
+	}
+}
@@ -1,6 +1,7 @@
 package grbl
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -10,34 +11,70 @@ import (
 	"github.com/mastercactapus/gg/gcode"
 )
 
+// PlannerBlocks is the number of motion blocks Grbl can buffer in a default build, used until
+// the actual size is known (see Buffers). Lines acknowledged with `ok` may still be waiting in the planner.
+const PlannerBlocks = 15
+
 type Grbl struct {
 	c *Client
 
 	l        *log.Logger
 	s        Status
 	settings Settings
+	params   Parameters
+	info     Info
+
+	// buildInfo and bfSizes are set once the buffer sizes are known from
+	// the build info or a status report.
+	buildInfo bool
+	bfSizes   bool
 
+	// statusCh and settingsCh are for Status and Settings, and otherwise
+	// are like any other subscription.
 	statusCh   chan Status
 	settingsCh chan Settings
+	subs       *subscribers
+	syncCh     chan func()
+	bannerCh   chan struct{}
+	connCh     chan ConnectionState
+
+	// t is set when using a Transport
+	t *Transport
 }
 
+// NewGrbl will create a new Grbl using rwc. If rwc is a Transport, the handshake is
+// performed again each time it reconnects (see Connection).
 func NewGrbl(rwc io.ReadWriteCloser) *Grbl {
-	return NewGrblClient(NewClient(rwc, ModeCharacterCount))
+	g := NewGrblClient(NewClient(rwc, ModeCharacterCount))
+	if t, ok := rwc.(*Transport); ok {
+		g.t = t
+		go g.watchTransport(t)
+	}
+	return g
 }
 func NewGrblClient(c *Client) *Grbl {
 	g := &Grbl{
-		c: c,
+		c:    c,
+		s:    Status{Buffers: Buffers{PlannerBlocks: PlannerBlocks, RXBytes: DefaultRXBufferSize}},
+		info: Info{Axes: 3},
 
 		l: log.New(ioutil.Discard, "", 0),
 
-		statusCh:   make(chan Status),
+		statusCh:   make(chan Status, 1),
 		settingsCh: make(chan Settings, 1),
+		subs:       newSubscribers(),
+		syncCh:     make(chan func()),
+		bannerCh:   make(chan struct{}, 1),
+		connCh:     make(chan ConnectionState, 1),
 	}
 	go g.loop()
 	return g
 }
 func (g *Grbl) SetLogger(l *log.Logger) {
 	g.l = l
+	if g.t != nil {
+		g.t.SetLogger(l)
+	}
 }
 
 func (g *Grbl) SerialMode() ClientMode {
@@ -52,44 +89,106 @@ func (g *Grbl) loop() {
 	for {
 		select {
 		case data := <-pCh:
-			if data[0] == '<' {
-				s, err := parseMachineStatus(string(data))
-				if err != nil {
-					g.l.Println("parse fail:", data[0], err)
-					continue
+			g.handlePush(data)
+		case fn := <-g.syncCh:
+			// process any messages that arrived before the response
+		drain:
+			for {
+				select {
+				case data := <-pCh:
+					g.handlePush(data)
+				default:
+					break drain
 				}
-				g.mergeStatus(s)
-				g.statusCh <- g.s
-				continue
 			}
+			fn()
+		}
+	}
+}
 
-			if data[0] == '$' {
-				g.settings.parseSetting(g.l, data)
-				continue
-			}
+// sync will call fn from the loop after all pending push messages have been processed.
+func (g *Grbl) sync(fn func()) {
+	done := make(chan struct{})
+	g.syncCh <- func() {
+		fn()
+		close(done)
+	}
+	<-done
+}
 
-			s := string(data)
-			if strings.HasPrefix(s, "Grbl") {
-				// version info
-				g.Settings()
-				continue
-			}
+func (g *Grbl) handlePush(data []byte) {
+	g.publishPush(data)
+	if data[0] == '<' {
+		s, err := parseMachineStatus(string(data))
+		if err != nil {
+			g.l.Println("parse fail:", data[0], err)
+			return
+		}
+		g.mergeStatus(s)
+		g.mergeBuffers(s)
+		g.publishStatus()
+		return
+	}
 
-			switch s {
-			case "[MSG:Enabled]":
-				g.s.State = StateCheck
-				g.statusCh <- g.s
-				continue
-			case "[MSG:Disabled]":
-				//resetting
-			}
-			g.l.Println("push:", s)
+	if data[0] == '$' {
+		g.settings.parseSetting(g.l, data)
+		return
+	}
+
+	if a, ok := parseAlarm(data); ok {
+		g.l.Println("alarm:", a.Description())
+		g.s.State = StateAlarm
+		g.s.Alarm = a
+		g.publishStatus()
+		return
+	}
+
+	if g.info.parse(data) {
+		if planner, rx, ok := parseBuildOptions(data); ok {
+			g.buildInfo = true
+			g.setBufferSizes(planner, rx)
+		}
+		return
+	}
+
+	ok, err := g.params.parse(data, g.settings.StatusReport.Inches)
+	if err != nil {
+		g.l.Println("parse fail:", err)
+	}
+	if ok {
+		return
+	}
+
+	s := string(data)
+	if strings.HasPrefix(s, "Grbl") {
+		// startup banner
+		if !g.info.parseBanner(data) {
+			g.l.Println("unknown version:", s)
 		}
+		select {
+		case g.bannerCh <- struct{}{}:
+		default:
+		}
+		g.Settings()
+		return
 	}
+
+	switch s {
+	case "[MSG:Enabled]":
+		g.s.State = StateCheck
+		g.publishStatus()
+		return
+	case "[MSG:Disabled]":
+		//resetting
+	}
+	g.l.Println("push:", s)
 }
 
 func (g *Grbl) mergeStatus(s *Status) {
 	g.s.State = s.State
+	if s.State != StateAlarm {
+		g.s.Alarm = 0
+	}
 	var mpos, wpos bool
 	for _, f := range s.Fields {
 		switch f {
@@ -108,10 +207,29 @@ func (g *Grbl) mergeStatus(s *Status) {
 			} else if wpos && !mpos {
 				g.makeMPos()
 			}
+		case "FS", "F":
+			g.s.FeedSpeed = s.FeedSpeed
+			g.s.SpindleSpeed = s.SpindleSpeed
 		case "Ov":
+			// accessory state is only reported along with overrides
 			g.s.FieldOverrides = s.FieldOverrides
+			g.s.Aux = s.Aux
+		case "Ln":
+			g.s.Line = s.Line
 		}
 	}
+	if !hasField(s.Fields, "Ln") {
+		g.s.Line = 0
+	}
+}
+
+func hasField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
 }
 
 func (g *Grbl) makeWPos() {
@@ -151,16 +269,40 @@ func (g *Grbl) Home() {
 	<-ch
 	g.Status()
 }
-func (g *Grbl) ExecLine(l gcode.Line) {
-	<-g.c.Execute([]byte(l.String() + "\n"))
+func (g *Grbl) ExecLine(l gcode.Line) error {
+	return responseErr(<-g.c.Execute([]byte(l.String() + "\n")))
+}
+
+// ExecLineContext will execute l, returning a *CommandError if it fails or ctx is done first.
+func (g *Grbl) ExecLineContext(ctx context.Context, l gcode.Line) error {
+	return g.c.ExecuteContext(ctx, []byte(l.String()+"\n"))
 }
 func (g *Grbl) Jog(l gcode.Line) {
 	g.c.Execute([]byte("$J=" + l.String() + "\n"))
 }
+
+// Status will request a status report. The returned channel holds only the latest status and is
+// shared by all callers; use Subscribe for independent listeners.
 func (g *Grbl) Status() chan Status {
 	g.c.Execute([]byte{byte(rtStatus)})
 	return g.statusCh
 }
+
+// StatusContext will request a status report and wait for it, until ctx is done.
+func (g *Grbl) StatusContext(ctx context.Context) (Status, error) {
+	ch, cancel := g.Subscribe()
+	defer cancel()
+	err := g.c.ExecuteContext(ctx, []byte{byte(rtStatus)})
+	if err != nil {
+		return Status{}, err
+	}
+	select {
+	case s := <-ch:
+		return s, nil
+	case <-ctx.Done():
+		return Status{}, ctx.Err()
+	}
+}
 func (g *Grbl) SoftReset() {
 	<-g.c.Execute([]byte{byte(rtSoftReset)})
 	g.Status()
@@ -170,42 +312,222 @@ func (g *Grbl) StartResume() {
 	g.Status()
 }
 
+func (g *Grbl) realtime(cmds ...rt) {
+	for _, c := range cmds {
+		<-g.c.Execute([]byte{byte(c)})
+	}
+	g.Status()
+}
+
+// SafetyDoor will act as if the safety door was opened.
+func (g *Grbl) SafetyDoor() {
+	g.realtime(rtSafetyDoor)
+}
+
+// FeedOverride will adjust the feed override by delta percent. Grbl limits the value to 10-200%.
+func (g *Grbl) FeedOverride(delta int) {
+	g.realtime(adjustCommands(delta, rtFeedOvCoarseUp, rtFeedOvCoarseDown, rtFeedOvFineUp, rtFeedOvFineDown)...)
+}
+
+// FeedOverrideReset will set the feed override to 100%.
+func (g *Grbl) FeedOverrideReset() {
+	g.realtime(rtFeedOvReset)
+}
+
+// RapidOverride will set the rapid override percentage, which must be one of 100, 50, or 25.
+func (g *Grbl) RapidOverride(pct int) error {
+	switch pct {
+	case 100:
+		g.realtime(rtRapidOvReset)
+	case 50:
+		g.realtime(rtRapidOvMedium)
+	case 25:
+		g.realtime(rtRapidOvLow)
+	default:
+		return errors.New("rapid override must be 100, 50, or 25 percent")
+	}
+	return nil
+}
+
+// SpindleOverride will adjust the spindle speed override by delta percent. Grbl limits the value to 10-200%.
+func (g *Grbl) SpindleOverride(delta int) {
+	g.realtime(adjustCommands(delta, rtSpindleOvCoarseUp, rtSpindleOvCoarseDown, rtSpindleOvFineUp, rtSpindleOvFineDown)...)
+}
+
+// SpindleOverrideReset will set the spindle speed override to 100%.
+func (g *Grbl) SpindleOverrideReset() {
+	g.realtime(rtSpindleOvReset)
+}
+
+// ToggleSpindleStop will stop or restart the spindle. It is only valid during a feed hold.
+func (g *Grbl) ToggleSpindleStop() {
+	g.realtime(rtSpindleOvStop)
+}
+
+// ToggleFlood will toggle flood coolant.
+func (g *Grbl) ToggleFlood() {
+	g.realtime(rtFloodToggle)
+}
+
+// ToggleMist will toggle mist coolant.
+func (g *Grbl) ToggleMist() {
+	g.realtime(rtMistToggle)
+}
+
+// Parameters will read the coordinate system offsets and other parameters from Grbl.
+//
+// It must not be called from the same goroutine that receives from Status().
+func (g *Grbl) Parameters() (*Parameters, error) {
+	err := responseErr(<-g.c.Execute([]byte("$#\n")))
+	if err != nil {
+		return nil, err
+	}
+	err = responseErr(<-g.c.Execute([]byte("$G\n")))
+	if err != nil {
+		return nil, err
+	}
+	var p Parameters
+	g.sync(func() { p = g.params })
+	return &p, nil
+}
+
+// ParametersContext is like Parameters, but returns a *CommandError if ctx is done first.
+func (g *Grbl) ParametersContext(ctx context.Context) (*Parameters, error) {
+	err := g.c.ExecuteContext(ctx, []byte("$#\n"))
+	if err != nil {
+		return nil, err
+	}
+	err = g.c.ExecuteContext(ctx, []byte("$G\n"))
+	if err != nil {
+		return nil, err
+	}
+	var p Parameters
+	g.sync(func() { p = g.params })
+	return &p, nil
+}
+
+// axisWords returns the X, Y, and Z words of axes, or an error if it contains any other word.
+func axisWords(axes gcode.Line) (gcode.Line, error) {
+	if len(axes) == 0 {
+		return nil, errors.New("no axes specified")
+	}
+	for _, w := range axes {
+		switch w.Type {
+		case 'X', 'Y', 'Z':
+		default:
+			return nil, errors.New("invalid axis word " + w.String())
+		}
+	}
+	return axes, nil
+}
+
+func g10(l float64, p int, axes gcode.Line) (gcode.Line, error) {
+	if p < 0 || p > 6 {
+		return nil, errors.New("coordinate system must be 0 (active) or 1-6 (G54-G59)")
+	}
+	axes, err := axisWords(axes)
+	if err != nil {
+		return nil, err
+	}
+	return append(gcode.Line{
+		{Type: 'G', Value: 10},
+		{Type: 'L', Value: l},
+		{Type: 'P', Value: float64(p)},
+	}, axes...), nil
+}
+
+// SetWorkOffset will set the offset of the given axes for a coordinate system (1-6 for G54-G59,
+// or 0 for the active one) using G10 L2. Values are in the active units.
+//
+// Offsets are stored in Grbl's EEPROM and persist across resets.
+func (g *Grbl) SetWorkOffset(p int, axes gcode.Line) error {
+	return g.SetWorkOffsetContext(context.Background(), p, axes)
+}
+
+// SetWorkOffsetContext is like SetWorkOffset, but returns a *CommandError if ctx is done first.
+func (g *Grbl) SetWorkOffsetContext(ctx context.Context, p int, axes gcode.Line) error {
+	l, err := g10(2, p, axes)
+	if err != nil {
+		return err
+	}
+	return g.ExecLineContext(ctx, l)
+}
+
+// SetWorkZero will set the offset of a coordinate system (1-6 for G54-G59, or 0 for the active one)
+// so that the current position has the given value for each axis, using G10 L20.
+//
+// Offsets are stored in Grbl's EEPROM and persist across resets.
+func (g *Grbl) SetWorkZero(p int, axes gcode.Line) error {
+	return g.SetWorkZeroContext(context.Background(), p, axes)
+}
+
+// SetWorkZeroContext is like SetWorkZero, but returns a *CommandError if ctx is done first.
+func (g *Grbl) SetWorkZeroContext(ctx context.Context, p int, axes gcode.Line) error {
+	l, err := g10(20, p, axes)
+	if err != nil {
+		return err
+	}
+	return g.ExecLineContext(ctx, l)
+}
+
+// SelectWCS will set the active work coordinate system (54-59).
+func (g *Grbl) SelectWCS(wcs int) error {
+	return g.SelectWCSContext(context.Background(), wcs)
+}
+
+// SelectWCSContext is like SelectWCS, but returns a *CommandError if ctx is done first.
+func (g *Grbl) SelectWCSContext(ctx context.Context, wcs int) error {
+	if wcs < 54 || wcs > 59 {
+		return errors.New("coordinate system must be 54-59")
+	}
+	return g.ExecLineContext(ctx, gcode.Line{{Type: 'G', Value: float64(wcs)}})
+}
+
 type CheckStatus struct {
 	Line int
 	Err  error
 }
 
+// Settings will request the settings, along with the build info (for buffer sizes). The returned
+// channel holds only the latest settings; use SubscribeSettings for independent listeners.
 func (g *Grbl) Settings() chan Settings {
+	info := g.c.Execute([]byte("$I\n"))
 	resp := g.c.Execute([]byte("$$\n"))
 	go func() {
-		d := <-resp
-		if d.Err != nil {
-			g.l.Println("failed to get settings:", d.Err)
+		err := responseErr(<-info)
+		if err != nil {
+			g.l.Println("failed to get build info:", err)
+		}
+		err = responseErr(<-resp)
+		if err != nil {
+			g.l.Println("failed to get settings:", err)
 			return
 		}
-		g.settingsCh <- g.settings
+		var s Settings
+		g.sync(func() { s = g.settings })
+		g.publishSettings(s)
 	}()
 	return g.settingsCh
 }
 
 func (g *Grbl) RunGCode(lines []gcode.Line) chan CheckStatus {
+	return g.RunGCodeContext(context.Background(), lines)
+}
+
+// RunGCodeContext will stream lines to Grbl, reporting the result of each. If ctx is done, lines
+// not yet sent are discarded and reported with ctx.Err(), as are any lines still waiting for a response.
+func (g *Grbl) RunGCodeContext(ctx context.Context, lines []gcode.Line) chan CheckStatus {
 	var cmds [][]byte
 	for _, l := range lines {
 		cmds = append(cmds, []byte(l.String()+"\n"))
 	}
 	ch := make(chan CheckStatus, len(lines))
 	go func() {
-		resp := g.c.ExecuteMany(cmds)
+		resp := g.c.ExecuteManyContext(ctx, cmds)
 		var r *Response
 		for i := range cmds {
 			r = <-resp
-			if r.Err != nil {
-				ch <- CheckStatus{Line: i, Err: r.Err}
-			} else if r.Data[0] == 'e' {
-				ch <- CheckStatus{Line: i, Err: errors.New(string(r.Data))}
-			} else {
-				ch <- CheckStatus{Line: i}
-			}
+			ch <- CheckStatus{Line: i, Err: responseErr(r)}
 		}
 		close(ch)
 	}()
@@ -230,13 +552,7 @@ func (g *Grbl) CheckGCode(lines []gcode.Line) chan CheckStatus {
 			if i <= 0 || i == max-1 {
 				continue
 			}
-			if r.Err != nil {
-				ch <- CheckStatus{Line: i, Err: r.Err}
-			} else if r.Data[0] == 'e' {
-				ch <- CheckStatus{Line: i, Err: errors.New(string(r.Data))}
-			} else {
-				ch <- CheckStatus{Line: i}
-			}
+			ch <- CheckStatus{Line: i, Err: responseErr(r)}
 		}
 		close(ch)
 	}()
@@ -0,0 +1,115 @@
+package grbl
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Info is the version and build of the connected Grbl.
+type Info struct {
+	// Version is from the startup banner (e.g. "1.1f").
+	Version      string
+	Major, Minor int
+
+	// Build is the version and build date from `$I` (e.g. "1.1f.20170801"), and Options
+	// are the build option letters (e.g. "VL").
+	Build   string
+	Options string
+
+	VariableSpindle bool
+	CoolantMist     bool
+	SafetyDoor      bool
+	LineNumbers     bool
+
+	// LaserMode is set when laser mode ($32) is enabled.
+	LaserMode bool
+
+	// Axes is the number of axes, which is 3 unless reported (e.g. by grblHAL).
+	Axes int
+}
+
+// HasOption returns true if the build option letter (e.g. 'V' for variable spindle) was reported.
+func (i Info) HasOption(o byte) bool {
+	return strings.IndexByte(i.Options, o) != -1
+}
+
+// spaces are removed by the Client
+var bannerRx = regexp.MustCompile(`^Grbl(?:HAL)?([0-9]+)\.([0-9]+)([a-z]?)\[`)
+
+// parseBanner will update the version from the startup banner (e.g. `Grbl1.1f['$'forhelp]`).
+func (i *Info) parseBanner(data []byte) bool {
+	m := bannerRx.FindSubmatch(data)
+	if m == nil {
+		return false
+	}
+	i.Major, _ = strconv.Atoi(string(m[1]))
+	i.Minor, _ = strconv.Atoi(string(m[2]))
+	i.Version = string(m[1]) + "." + string(m[2]) + string(m[3])
+	return true
+}
+
+// parse will update i from a single line of `$I` output, returning false if it is not build info.
+func (i *Info) parse(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("[")) || !bytes.HasSuffix(data, []byte("]")) {
+		return false
+	}
+	parts := strings.Split(string(data[1:len(data)-1]), ":")
+	if len(parts) < 2 {
+		return false
+	}
+	switch parts[0] {
+	case "VER":
+		i.Build = parts[1]
+	case "OPT":
+		i.Options = strings.SplitN(parts[1], ",", 2)[0]
+		i.VariableSpindle = i.HasOption('V')
+		i.CoolantMist = i.HasOption('M')
+		i.SafetyDoor = i.HasOption('+')
+		i.LineNumbers = i.HasOption('N')
+	case "AXS":
+		// grblHAL, e.g. `[AXS:4:XYZA]`
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return false
+		}
+		i.Axes = n
+	default:
+		return false
+	}
+	return true
+}
+
+// Info returns the version and build of the connected Grbl.
+func (g *Grbl) Info() Info {
+	var i Info
+	g.sync(func() {
+		i = g.info
+		i.LaserMode = g.settings.LaserMode
+	})
+	return i
+}
+
+// Connect will wait for the startup banner, performing a soft reset if it isn't received
+// within timeout, then read the build info.
+func (g *Grbl) Connect(timeout time.Duration) (Info, error) {
+	select {
+	case <-g.bannerCh:
+	case <-time.After(timeout):
+		<-g.c.Execute([]byte{byte(rtSoftReset)})
+		select {
+		case <-g.bannerCh:
+		case <-time.After(timeout):
+			return Info{}, errors.New("no startup message from Grbl")
+		}
+	}
+
+	err := responseErr(<-g.c.Execute([]byte("$I\n")))
+	if err != nil {
+		return Info{}, err
+	}
+	return g.Info(), nil
+}
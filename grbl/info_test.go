@@ -0,0 +1,49 @@
+package grbl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mastercactapus/gg/grbl/sim"
+)
+
+func TestInfo_Parse(t *testing.T) {
+	var i Info
+	for _, s := range []string{"GrblHAL1.1f['$'or'$HELP'forhelp]", "[VER:1.1f.20211002:]", "[OPT:VNMSL+,35,1024,4,0]", "[AXS:4:XYZA]"} {
+		if !i.parseBanner([]byte(s)) && !i.parse([]byte(s)) {
+			t.Errorf("%s: not parsed", s)
+		}
+	}
+	if i.Version != "1.1f" || i.Major != 1 || i.Minor != 1 || i.Build != "1.1f.20211002" {
+		t.Errorf("version = %s (%d.%d), %s; want 1.1f (1.1), 1.1f.20211002", i.Version, i.Major, i.Minor, i.Build)
+	}
+	if !i.VariableSpindle || !i.CoolantMist || !i.SafetyDoor || !i.LineNumbers || i.Axes != 4 {
+		t.Errorf("info = %+v; want all capabilities and 4 axes", i)
+	}
+	if i.parse([]byte("[G54:0.000,0.000,0.000]")) {
+		t.Error("parse(G54) = true; want false")
+	}
+}
+
+func TestGrbl_Connect(t *testing.T) {
+	m := sim.NewMachine()
+	defer m.Close()
+	g := NewGrbl(m)
+
+	info, err := g.Connect(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != sim.Version || info.Build != sim.Version+".20170801" {
+		t.Errorf("version = %s, %s; want %s", info.Version, info.Build, sim.Version)
+	}
+	if !info.VariableSpindle || info.CoolantMist || info.Axes != 3 {
+		t.Errorf("info = %+v; want variable spindle, 3 axes", info)
+	}
+
+	// the banner was already received, so a reset is needed
+	_, err = g.Connect(50 * time.Millisecond)
+	if err != nil {
+		t.Errorf("reconnect: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package grbl
+
+import (
+	"math"
+	"time"
+)
+
+// JogBlocks returns the number of continuous jog increments to keep queued in a planner
+// of the given size (see Buffers.PlannerBlocks).
+func JogBlocks(planner int) int {
+	if planner < 4 {
+		return 2
+	}
+	return planner / 2
+}
+
+// jogMinTime is the shortest duration of a continuous jog increment, so that the queued
+// increments last longer than the time between status reports.
+const jogMinTime = 50 * time.Millisecond
+
+// JogIncrement is a single move of a continuous jog.
+type JogIncrement struct {
+	Distance float64 // mm
+	Rate     float64 // mm/min
+	Time     time.Duration
+}
+
+// ContinuousJog returns the increment to stream to jog along an axis (0, 1 or 2 for X, Y or Z) at rate
+// (mm/min). The rate is limited to the max rate of the axis. Increments are long enough that Grbl can
+// decelerate to a stop within the number queued (see JogBlocks), so motion doesn't slow between increments.
+//
+// False is returned if the rate or acceleration of the axis is unknown.
+func (s Settings) ContinuousJog(axis int, rate float64, blocks int) (JogIncrement, bool) {
+	rates := [3]Rate{s.MaxRate.X, s.MaxRate.Y, s.MaxRate.Z}
+	accels := [3]Accel{s.MaxAcceleration.X, s.MaxAcceleration.Y, s.MaxAcceleration.Z}
+	if rates[axis] == (Rate{}) || accels[axis] == (Accel{}) || rate <= 0 || blocks < 2 {
+		return JogIncrement{}, false
+	}
+	rate = math.Min(rate, rates[axis].MillimetersPerMinute())
+
+	v := rate / 60 // mm/sec
+	a := accels[axis].MMSec2()
+	// stopping takes v^2/2a mm, spread over the increments after the current one
+	t := time.Duration(v / (2 * a * float64(blocks-1)) * float64(time.Second))
+	if t < jogMinTime {
+		t = jogMinTime
+	}
+
+	return JogIncrement{
+		Distance: v * t.Seconds(),
+		Rate:     rate,
+		Time:     t,
+	}, true
+}
@@ -0,0 +1,50 @@
+package grbl
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSettings_ContinuousJog(t *testing.T) {
+	s := testSettings()
+
+	// slow jogs use the shortest increment
+	inc, ok := s.ContinuousJog(0, 60, JogBlocks(PlannerBlocks))
+	if !ok {
+		t.Fatal("ok = false; want true")
+	}
+	if inc.Time != jogMinTime || math.Abs(inc.Distance-0.05) > 1e-9 {
+		t.Errorf("got %v, %gmm; want %v, 0.05mm", inc.Time, inc.Distance, jogMinTime)
+	}
+
+	// limited to 1000mm/min at 10mm/sec^2, needs v^2/2a = 13.9mm to stop
+	inc, ok = s.ContinuousJog(2, 5000, JogBlocks(PlannerBlocks))
+	if !ok {
+		t.Fatal("ok = false; want true")
+	}
+	if inc.Rate != 1000 {
+		t.Errorf("Rate = %g; want 1000", inc.Rate)
+	}
+	// 13.9mm over 6 increments
+	if inc.Time != 138888888*time.Nanosecond || math.Abs(inc.Distance-2.3148148) > 1e-6 {
+		t.Errorf("got %v, %gmm; want 138.888888ms, 2.3148148mm", inc.Time, inc.Distance)
+	}
+	stop := 1000.0 / 60 * 1000 / 60 / 20
+	if d := inc.Distance * float64(JogBlocks(PlannerBlocks)-1); math.Abs(d-stop) > 1e-6 {
+		t.Errorf("queued distance = %gmm; want %gmm", d, stop)
+	}
+
+	if _, ok = (Settings{}).ContinuousJog(0, 100, JogBlocks(PlannerBlocks)); ok {
+		t.Error("ok = true; want false without settings")
+	}
+
+	// larger planners (e.g. grblHAL) spread the stop over more increments
+	if n := JogBlocks(35); n != 17 {
+		t.Errorf("JogBlocks(35) = %d; want 17", n)
+	}
+	inc, _ = s.ContinuousJog(2, 5000, JogBlocks(35))
+	if d := inc.Distance * float64(JogBlocks(35)-1); math.Abs(d-stop) > 1e-6 {
+		t.Errorf("queued distance = %gmm; want %gmm", d, stop)
+	}
+}
@@ -0,0 +1,103 @@
+package grbl
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Parameters holds the coordinate data reported by the `$#` command, as well as the
+// active coordinate system. All values are in mm.
+type Parameters struct {
+	// WCS holds the offsets of the G54-G59 work coordinate systems.
+	WCS [6][3]float64
+
+	// ActiveWCS is the selected coordinate system (54-59), or 0 if unknown.
+	ActiveWCS int
+
+	G28, G30 [3]float64
+	G92      [3]float64
+
+	// TLO is the tool length offset.
+	TLO float64
+
+	// Probe is the machine position of the last probe cycle.
+	Probe        [3]float64
+	ProbeSuccess bool
+}
+
+// Offset returns the offset of the given coordinate system (54-59).
+func (p Parameters) Offset(wcs int) [3]float64 {
+	if wcs < 54 || wcs > 59 {
+		return [3]float64{}
+	}
+	return p.WCS[wcs-54]
+}
+
+var wcsRx = regexp.MustCompile(`G(5[4-9])(?:[^0-9.]|$)`)
+
+func parseCoords(s string, inches bool) (c [3]float64, err error) {
+	vals, err := parseFloats(s)
+	if err != nil {
+		return c, err
+	}
+	if len(vals) < 3 {
+		return c, errors.Errorf("expected 3 values but got %d", len(vals))
+	}
+	copy(c[:], vals)
+	if inches {
+		for i := range c {
+			c[i] *= 25.4
+		}
+	}
+	return c, nil
+}
+
+// parse will update p from a single line of `$#` or `$G` output (e.g. `[G54:0.000,0.000,0.000]`),
+// returning false if the line is not a parameter.
+func (p *Parameters) parse(data []byte, inches bool) (bool, error) {
+	if !bytes.HasPrefix(data, []byte("[")) || !bytes.HasSuffix(data, []byte("]")) {
+		return false, nil
+	}
+	parts := strings.SplitN(string(data[1:len(data)-1]), ":", 2)
+	if len(parts) != 2 {
+		return false, nil
+	}
+
+	var err error
+	switch parts[0] {
+	case "G54", "G55", "G56", "G57", "G58", "G59":
+		n, _ := strconv.Atoi(parts[0][1:])
+		p.WCS[n-54], err = parseCoords(parts[1], inches)
+	case "G28":
+		p.G28, err = parseCoords(parts[1], inches)
+	case "G30":
+		p.G30, err = parseCoords(parts[1], inches)
+	case "G92":
+		p.G92, err = parseCoords(parts[1], inches)
+	case "TLO":
+		p.TLO, err = strconv.ParseFloat(parts[1], 64)
+		if inches {
+			p.TLO *= 25.4
+		}
+	case "PRB":
+		vals := strings.SplitN(parts[1], ":", 2)
+		p.Probe, err = parseCoords(vals[0], inches)
+		p.ProbeSuccess = len(vals) == 2 && vals[1] == "1"
+	case "GC":
+		// spaces are removed by the Client
+		m := wcsRx.FindStringSubmatch(parts[1])
+		if m != nil {
+			p.ActiveWCS, _ = strconv.Atoi(m[1])
+		}
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, errors.Wrap(err, "parse "+parts[0])
+	}
+	return true, nil
+}
@@ -0,0 +1,85 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl/sim"
+)
+
+func TestParameters_Parse(t *testing.T) {
+	var p Parameters
+	check := func(line string, inches bool) {
+		ok, err := p.parse([]byte(line), inches)
+		if err != nil {
+			t.Fatalf("parse %s: %v", line, err)
+		}
+		if !ok {
+			t.Fatalf("parse %s: not recognized", line)
+		}
+	}
+	check("[G55:1.000,-2.500,3.000]", false)
+	check("[G92:1.000,0.000,0.000]", true)
+	check("[TLO:1.500]", false)
+	check("[PRB:-10.000,-20.000,-30.000:1]", false)
+	check("[GC:G0G55G17G21G90G94M5M9T0F0S0]", false)
+
+	if p.Offset(55) != [3]float64{1, -2.5, 3} {
+		t.Errorf("G55 = %v; want [1 -2.5 3]", p.Offset(55))
+	}
+	if p.G92 != [3]float64{25.4, 0, 0} {
+		t.Errorf("G92 = %v; want [25.4 0 0]", p.G92)
+	}
+	if p.TLO != 1.5 {
+		t.Errorf("TLO = %f; want 1.5", p.TLO)
+	}
+	if p.Probe != [3]float64{-10, -20, -30} || !p.ProbeSuccess {
+		t.Errorf("Probe = %v, %t; want [-10 -20 -30], true", p.Probe, p.ProbeSuccess)
+	}
+	if p.ActiveWCS != 55 {
+		t.Errorf("ActiveWCS = %d; want 55", p.ActiveWCS)
+	}
+
+	ok, _ := p.parse([]byte("[MSG:Caution: Unlocked]"), false)
+	if ok {
+		t.Error("parse [MSG:...] = true; want false")
+	}
+}
+
+func TestGrbl_WorkOffsets(t *testing.T) {
+	m := sim.NewMachine()
+	defer m.Close()
+	g := NewGrbl(m)
+	<-g.Settings()
+
+	err := g.SelectWCS(56)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.SetWorkZero(0, gcode.Line{{Type: 'X'}, {Type: 'Y', Value: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.SetWorkOffset(1, gcode.Line{{Type: 'Z', Value: -3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.SetWorkOffset(7, gcode.Line{{Type: 'Z'}})
+	if err == nil {
+		t.Error("SetWorkOffset(7) err = nil; want error")
+	}
+
+	p, err := g.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ActiveWCS != 56 {
+		t.Errorf("ActiveWCS = %d; want 56", p.ActiveWCS)
+	}
+	if p.Offset(56) != [3]float64{0, -5, 0} {
+		t.Errorf("G56 = %v; want [0 -5 0]", p.Offset(56))
+	}
+	if p.Offset(54) != [3]float64{0, 0, -3} {
+		t.Errorf("G54 = %v; want [0 0 -3]", p.Offset(54))
+	}
+}
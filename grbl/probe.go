@@ -0,0 +1,114 @@
+package grbl
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+// ProbeMode selects the G38 probing command.
+type ProbeMode float64
+
+// Probe modes
+const (
+	// ProbeToward moves until the probe is triggered, raising an alarm if it never is (G38.2).
+	ProbeToward ProbeMode = 38.2
+
+	// ProbeTowardNoError moves until the probe is triggered (G38.3).
+	ProbeTowardNoError ProbeMode = 38.3
+
+	// ProbeAway moves until the probe is released, raising an alarm if it never is (G38.4).
+	ProbeAway ProbeMode = 38.4
+
+	// ProbeAwayNoError moves until the probe is released (G38.5).
+	ProbeAwayNoError ProbeMode = 38.5
+)
+
+// ErrNoProbeResult is returned when a probe cycle did not report a result (e.g. the probe
+// was already in the triggered state).
+var ErrNoProbeResult = errors.New("no probe result")
+
+// ProbeResult is the outcome of a probe cycle.
+type ProbeResult struct {
+	// Position is the machine position, in mm, where the probe changed state, or where
+	// motion stopped if it didn't.
+	Position [3]float64
+
+	Success bool
+}
+
+func isProbeReport(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("[PRB:"))
+}
+
+// Probe will run a probe cycle, moving the given axes by an incremental distance (in mm) at feed mm/min.
+//
+// Grbl is left in G21 and G90 (mm and absolute distance mode) afterwards. For ProbeToward and ProbeAway
+// the Alarm is returned along with the result if the probe did not change state; G90 can't be restored
+// while Grbl is in the alarm state, so it should be sent again after unlocking.
+func (g *Grbl) Probe(mode ProbeMode, axes gcode.Line, feed float64) (ProbeResult, error) {
+	axes, err := axisWords(axes)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	if feed <= 0 {
+		return ProbeResult{}, errors.New("feed rate must be positive")
+	}
+
+	l := append(gcode.Line{
+		{Type: 'G', Value: 21},
+		{Type: 'G', Value: 91},
+		{Type: 'G', Value: float64(mode)},
+	}, axes...)
+	l = append(l, gcode.Word{Type: 'F', Value: feed})
+
+	// subscribed first, so the result can't be missed
+	push, stop := g.SubscribePush()
+	defer stop()
+	err = responseErr(<-g.c.Execute([]byte(l.String() + "\n")))
+
+	// restore absolute mode, even if probing failed
+	absErr := g.ExecLine(gcode.Line{{Type: 'G', Value: 90}})
+	if err != nil {
+		return ProbeResult{}, err
+	}
+
+	// the result is reported before the response, so it has been published once the
+	// push messages before it are processed
+	var inches bool
+	var alarm Alarm
+	g.sync(func() {
+		inches = g.settings.StatusReport.Inches
+		alarm = g.s.Alarm
+	})
+	var p Parameters
+	var reported bool
+read:
+	for {
+		select {
+		case data := <-push:
+			if !isProbeReport(data) {
+				continue
+			}
+			reported = true
+			if _, err := p.parse(data, inches); err != nil {
+				return ProbeResult{}, err
+			}
+		default:
+			break read
+		}
+	}
+	r := ProbeResult{Position: p.Probe, Success: p.ProbeSuccess}
+
+	switch {
+	case !reported && alarm != 0:
+		return r, alarm
+	case !reported:
+		return r, ErrNoProbeResult
+	case !r.Success && (mode == ProbeToward || mode == ProbeAway):
+		return r, AlarmProbeContact
+	}
+
+	return r, absErr
+}
@@ -0,0 +1,51 @@
+package grbl
+
+import (
+	"testing"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl/sim"
+)
+
+func TestGrbl_Probe(t *testing.T) {
+	m := sim.NewMachine()
+	defer m.Close()
+	m.SetSpeed(100)
+	m.SetStock([3]float64{-100, -100, -200}, [3]float64{0, 0, -20})
+	g := NewGrbl(m)
+	<-g.Settings()
+	go func() {
+		for range g.statusCh {
+		}
+	}()
+
+	r, err := g.Probe(ProbeToward, gcode.Line{{Type: 'Z', Value: -50}}, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Success || r.Position != [3]float64{0, 0, -20} {
+		t.Errorf("result = %+v; want success at [0 0 -20]", r)
+	}
+
+	_, err = g.Probe(ProbeToward, gcode.Line{{Type: 'Q', Value: -50}}, 500)
+	if err == nil {
+		t.Error("Probe(Q) err = nil; want error")
+	}
+
+	err = g.ExecLine(gcode.Line{{Type: 'G', Value: 0}, {Type: 'Z', Value: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = g.Probe(ProbeTowardNoError, gcode.Line{{Type: 'X', Value: 5}}, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Success || r.Position != [3]float64{5, 0, 0} {
+		t.Errorf("result = %+v; want failure at [5 0 0]", r)
+	}
+
+	_, err = g.Probe(ProbeToward, gcode.Line{{Type: 'X', Value: 5}}, 500)
+	if err != AlarmProbeContact {
+		t.Errorf("err = %v; want %v", err, AlarmProbeContact)
+	}
+}
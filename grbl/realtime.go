@@ -8,4 +8,42 @@ const (
 	rtStatus      rt = '?'
 	rtStartResume rt = '~'
 	rtFeedHold    rt = '!'
+	rtSafetyDoor  rt = 0x84
+
+	rtFeedOvReset      rt = 0x90
+	rtFeedOvCoarseUp   rt = 0x91
+	rtFeedOvCoarseDown rt = 0x92
+	rtFeedOvFineUp     rt = 0x93
+	rtFeedOvFineDown   rt = 0x94
+
+	rtRapidOvReset  rt = 0x95
+	rtRapidOvMedium rt = 0x96
+	rtRapidOvLow    rt = 0x97
+
+	rtSpindleOvReset      rt = 0x99
+	rtSpindleOvCoarseUp   rt = 0x9A
+	rtSpindleOvCoarseDown rt = 0x9B
+	rtSpindleOvFineUp     rt = 0x9C
+	rtSpindleOvFineDown   rt = 0x9D
+	rtSpindleOvStop       rt = 0x9E
+
+	rtFloodToggle rt = 0xA0
+	rtMistToggle  rt = 0xA1
 )
+
+// adjustCommands returns the coarse (10%) and fine (1%) commands needed to adjust an override by delta percent.
+func adjustCommands(delta int, coarseUp, coarseDown, fineUp, fineDown rt) []rt {
+	var cmds []rt
+	coarse, fine := coarseUp, fineUp
+	if delta < 0 {
+		delta = -delta
+		coarse, fine = coarseDown, fineDown
+	}
+	for ; delta >= 10; delta -= 10 {
+		cmds = append(cmds, coarse)
+	}
+	for ; delta > 0; delta-- {
+		cmds = append(cmds, fine)
+	}
+	return cmds
+}
@@ -0,0 +1,18 @@
+package grbl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdjustCommands(t *testing.T) {
+	check := func(delta int, exp []rt) {
+		cmds := adjustCommands(delta, rtFeedOvCoarseUp, rtFeedOvCoarseDown, rtFeedOvFineUp, rtFeedOvFineDown)
+		if !reflect.DeepEqual(cmds, exp) {
+			t.Errorf("adjustCommands(%d) = %v; want %v", delta, cmds, exp)
+		}
+	}
+	check(0, nil)
+	check(23, []rt{rtFeedOvCoarseUp, rtFeedOvCoarseUp, rtFeedOvFineUp, rtFeedOvFineUp, rtFeedOvFineUp})
+	check(-11, []rt{rtFeedOvCoarseDown, rtFeedOvFineDown})
+}
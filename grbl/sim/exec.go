@@ -0,0 +1,441 @@
+package sim
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+// Grbl error codes
+const (
+	errExpectedCommand   = 1
+	errBadNumber         = 2
+	errInvalidStatement  = 3
+	errNegativeValue     = 4
+	errSettingDisabled   = 5
+	errIdleError         = 8
+	errSystemGCLock      = 9
+	errSoftLimitNoHoming = 10
+	errTravelExceeded    = 15
+	errInvalidJog        = 16
+	errUnsupportedCmd    = 20
+	errModalViolation    = 21
+	errUndefinedFeed     = 22
+	errAxisWordsUnused   = 31
+	errArcNoAxisWords    = 32
+	errArcInvalidTarget  = 33
+	errArcRadius         = 34
+	errArcNoOffsets      = 35
+)
+
+// Grbl alarm codes
+const (
+	alarmSoftLimit   = 2
+	alarmAbortCycle  = 3
+	alarmProbeInit   = 4
+	alarmProbeFail   = 5
+	alarmHomingReset = 6
+)
+
+// execLine executes a single line from the RX buffer, returning the Grbl error code (0 for ok).
+func (m *Machine) execLine(line string) int {
+	if line[0] == '$' {
+		return m.execSystem(line)
+	}
+	switch m.state {
+	case stateAlarm, stateJog:
+		return errSystemGCLock
+	}
+	return m.execGCode(line, false)
+}
+
+func (m *Machine) idle() bool {
+	return m.state == stateIdle || m.state == stateAlarm || m.state == stateCheck
+}
+
+func (m *Machine) execSystem(line string) int {
+	cmd := strings.ToUpper(line[1:])
+	switch {
+	case cmd == "":
+		m.println("[HLP:$$ $# $G $I $N $x=val $Nx=line $J=line $SLP $C $X $H ~ ! ? ctrl-x]")
+	case cmd == "$":
+		if !m.idle() {
+			return errIdleError
+		}
+		for _, n := range m.settings.keys() {
+			m.println(m.settings.format(n))
+		}
+	case cmd == "#":
+		if m.state != stateIdle && m.state != stateAlarm {
+			return errIdleError
+		}
+		for i, c := range m.wcs {
+			m.println("[G" + itoa(54+i) + ":" + fmtPos(c) + "]")
+		}
+		m.println("[G28:" + fmtPos(m.g28) + "]")
+		m.println("[G30:" + fmtPos(m.g30) + "]")
+		m.println("[G92:" + fmtPos(m.parser.G92) + "]")
+		m.println("[TLO:" + ftoa(m.parser.ToolLengthOffset) + "]")
+		m.println("[PRB:" + fmtPos(m.prb) + ":" + boolString(m.prbOK) + "]")
+	case cmd == "G":
+		m.println("[GC:" + modalString(m.parser) + "]")
+	case cmd == "I":
+		m.println("[VER:" + Version + ".20170801:]")
+		m.println("[OPT:V," + itoa(PlannerSize) + "," + itoa(RXBufferSize) + "]")
+	case cmd == "N":
+		m.println("$N0=")
+		m.println("$N1=")
+	case cmd == "C":
+		switch m.state {
+		case stateCheck:
+			m.println("[MSG:Disabled]")
+			m.checkOff = true
+		case stateIdle:
+			m.state = stateCheck
+			m.println("[MSG:Enabled]")
+		default:
+			return errIdleError
+		}
+	case cmd == "X":
+		if m.state == stateAlarm {
+			m.println("[MSG:Caution: Unlocked]")
+			m.state = stateIdle
+		}
+	case cmd == "H":
+		if !m.settings.homing() {
+			return errSettingDisabled
+		}
+		if !m.idle() || m.state == stateCheck {
+			return errIdleError
+		}
+		m.home()
+		return -1
+	case strings.HasPrefix(cmd, "J="):
+		if m.state != stateIdle && m.state != stateJog {
+			return errIdleError
+		}
+		return m.execGCode(line[3:], true)
+	case len(cmd) > 0 && cmd[0] >= '0' && cmd[0] <= '9':
+		if m.state != stateIdle && m.state != stateAlarm {
+			return errIdleError
+		}
+		parts := strings.SplitN(cmd, "=", 2)
+		if len(parts) != 2 {
+			return errInvalidStatement
+		}
+		n, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return errBadNumber
+		}
+		v, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return errBadNumber
+		}
+		return m.settings.set(n, v)
+	default:
+		return errInvalidStatement
+	}
+	return 0
+}
+
+func fmtPos(p [3]float64) string {
+	return ftoa(p[0]) + "," + ftoa(p[1]) + "," + ftoa(p[2])
+}
+
+func modalString(s gcode.State) string {
+	g := func(v float64) string { return "G" + strconv.FormatFloat(v, 'f', -1, 64) }
+	parts := []string{
+		g(s.Motion),
+		"G" + itoa(s.WCS),
+		g(17 + float64(s.Plane)),
+		g(21 - float64(s.Units)),
+		g(90 + float64(s.Distance)),
+		g(94 - float64(s.FeedMode)),
+	}
+	switch s.Spindle {
+	case gcode.SpindleCW:
+		parts = append(parts, "M3")
+	case gcode.SpindleCCW:
+		parts = append(parts, "M4")
+	default:
+		parts = append(parts, "M5")
+	}
+	switch {
+	case s.Mist && s.Flood:
+		parts = append(parts, "M7", "M8")
+	case s.Mist:
+		parts = append(parts, "M7")
+	case s.Flood:
+		parts = append(parts, "M8")
+	default:
+		parts = append(parts, "M9")
+	}
+	parts = append(parts,
+		"T"+itoa(s.Tool),
+		"F"+strconv.FormatFloat(s.Feed, 'f', -1, 64),
+		"S"+strconv.FormatFloat(s.SpindleSpeed, 'f', -1, 64),
+	)
+	return strings.Join(parts, " ")
+}
+
+// wco returns the work coordinate offset for the parser state s.
+func (m *Machine) wco(s gcode.State) [3]float64 {
+	var o [3]float64
+	if s.WCS >= 54 && s.WCS <= 59 {
+		o = m.wcs[s.WCS-54]
+	}
+	for i := range o {
+		o[i] += s.G92[i]
+	}
+	o[2] += s.ToolLengthOffset
+	return o
+}
+
+func errCode(err error) int {
+	switch err {
+	case gcode.ErrArcNoAxisWords:
+		return errArcNoAxisWords
+	case gcode.ErrArcNoOffsets:
+		return errArcNoOffsets
+	case gcode.ErrArcInvalidTarget:
+		return errArcInvalidTarget
+	case gcode.ErrArcRadius:
+		return errArcRadius
+	}
+	if _, ok := err.(*gcode.SyntaxError); ok {
+		return errExpectedCommand
+	}
+	switch {
+	case errors.Is(err, gcode.ErrModalViolation):
+		return errModalViolation
+	case errors.Is(err, gcode.ErrAxisWordsUnused):
+		return errAxisWordsUnused
+	}
+	return errUnsupportedCmd
+}
+
+func hasG(l gcode.Line, v float64) bool {
+	for _, w := range l {
+		if w.Type == 'G' && w.Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAxis(l gcode.Line) bool {
+	return l.HasWord('X') || l.HasWord('Y') || l.HasWord('Z')
+}
+
+// execGCode will execute a line of g-code, or a jog command if jog is set.
+func (m *Machine) execGCode(text string, jog bool) int {
+	b, err := gcode.ParseLine(text)
+	if err != nil {
+		return errCode(err)
+	}
+	l := b.Line
+
+	check := m.state == stateCheck
+	if !check {
+		// sync position with the end of the planner
+		m.parser.Position = sub(m.tail, m.wco(m.parser))
+	}
+
+	in := gcode.NewInterpreter()
+	start := m.parser
+	if jog {
+		for _, w := range l {
+			switch {
+			case w.Type == 'G' && (w.Value == 20 || w.Value == 21 || w.Value == 90 || w.Value == 91 || w.Value == 53):
+			case w.Type == 'F' || axisIdx(w.Type) != -1:
+			default:
+				return errInvalidJog
+			}
+		}
+		if !l.HasWord('F') || !hasAxis(l) {
+			return errInvalidJog
+		}
+		start.Motion = gcode.MotionLinear
+		start.FeedMode = gcode.FeedUnitsPerMinute
+	}
+	in.SetState(start)
+	err = in.Exec(l)
+	if err != nil {
+		return errCode(err)
+	}
+	s := in.State()
+
+	isMove := hasAxis(l) && !hasG(l, 10) && !hasG(l, 92) && !hasG(l, 43.1)
+	if isMove && s.Motion != gcode.MotionRapid && s.Feed == 0 {
+		return errUndefinedFeed
+	}
+
+	switch {
+	case check:
+		m.parser = s
+		return 0
+	case !jog:
+		// jog commands do not alter the parser state
+		wco := m.wco(m.parser)
+		if code := m.execNonModal(l, s); code != 0 {
+			return code
+		}
+		m.parser = s
+		// report changes with the next status
+		if m.wco(s) != wco {
+			m.wcoCount = 0
+		}
+		if s.Spindle != start.Spindle || s.Flood != start.Flood || s.Mist != start.Mist {
+			m.ovCount = 0
+		}
+	}
+
+	if !isMove && !hasG(l, 28) && !hasG(l, 30) {
+		if hasG(l, 4) {
+			m.queue(block{target: m.tail, dwell: l.Value('P'), line: b.Number})
+		}
+		return 0
+	}
+
+	var pts [][3]float64
+	switch {
+	case hasG(l, 53):
+		t := m.tail
+		for _, w := range l {
+			if i := axisIdx(w.Type); i != -1 {
+				t[i] = s.Position[i]
+			}
+		}
+		pts = append(pts, t)
+	case hasG(l, 28) || hasG(l, 30):
+		if isMove {
+			pts = append(pts, add(s.Position, m.wco(s)))
+		}
+		if hasG(l, 28) {
+			pts = append(pts, m.g28)
+		} else {
+			pts = append(pts, m.g30)
+		}
+	case in.Arc() != nil:
+		for _, p := range in.Arc().Points(m.settings[12]) {
+			pts = append(pts, add(p, m.wco(s)))
+		}
+	default:
+		pts = append(pts, add(s.Position, m.wco(s)))
+	}
+
+	if m.settings.softLimits() && m.homed {
+		for _, p := range pts {
+			if m.inLimits(p) {
+				continue
+			}
+			if jog {
+				return errTravelExceeded
+			}
+			m.alarm(alarmSoftLimit)
+			return -1
+		}
+	}
+
+	if isProbe(s.Motion) && !jog && !hasG(l, 28) && !hasG(l, 30) {
+		return m.probe(s.Motion, pts[0], s.Feed, b.Number)
+	}
+
+	rapid := s.Motion == gcode.MotionRapid || hasG(l, 28) || hasG(l, 30)
+	var total float64
+	prev := m.tail
+	for _, p := range pts {
+		total += dist(prev, p)
+		prev = p
+	}
+	for _, p := range pts {
+		bl := block{target: p, rapid: rapid, jog: jog, line: b.Number}
+		if s.FeedMode == gcode.FeedInverseTime && !jog {
+			bl.feed = total * s.Feed
+		} else {
+			bl.feed = s.Feed
+		}
+		m.queue(bl)
+	}
+
+	return 0
+}
+
+// execNonModal handles commands that modify machine data not known to the Interpreter.
+func (m *Machine) execNonModal(l gcode.Line, s gcode.State) int {
+	switch {
+	case hasG(l, 10):
+		p := int(l.Value('P'))
+		if p == 0 {
+			p = s.WCS - 53
+		}
+		if p < 1 || p > 6 {
+			return errUnsupportedCmd
+		}
+		c := &m.wcs[p-1]
+		for _, w := range l {
+			i := axisIdx(w.Type)
+			if i == -1 {
+				continue
+			}
+			v := w.Value
+			if s.Units == gcode.UnitsInches {
+				v *= 25.4
+			}
+			switch l.Value('L') {
+			case 2:
+				c[i] = v
+			case 20:
+				c[i] = m.tail[i] - s.G92[i] - v
+				if i == 2 {
+					c[i] -= s.ToolLengthOffset
+				}
+			default:
+				return errUnsupportedCmd
+			}
+		}
+	case hasG(l, 28.1):
+		m.g28 = m.tail
+	case hasG(l, 30.1):
+		m.g30 = m.tail
+	}
+	return 0
+}
+
+func axisIdx(t byte) int {
+	switch t {
+	case 'X':
+		return 0
+	case 'Y':
+		return 1
+	case 'Z':
+		return 2
+	}
+	return -1
+}
+
+func (m *Machine) inLimits(p [3]float64) bool {
+	for i := range p {
+		if p[i] > 0 || p[i] < -m.settings.maxTravel(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// alarm will immediately stop all motion and enter the critical alarm state, where input is
+// ignored until a soft-reset. The position is lost, so homing is required again.
+func (m *Machine) alarm(code int) {
+	m.rx = m.rx[:0]
+	m.planner = m.planner[:0]
+	m.tail = m.mpos
+	m.homing = 0
+	m.probing = false
+	m.homed = false
+	m.state = stateAlarm
+	m.critical = true
+	m.println("ALARM:" + itoa(code))
+	m.println("[MSG:Reset to continue]")
+}
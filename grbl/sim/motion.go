@@ -0,0 +1,304 @@
+package sim
+
+import (
+	"math"
+	"time"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+type block struct {
+	target [3]float64
+	feed   float64 // mm/min
+	rapid  bool
+	jog    bool
+	line   int
+
+	// probe is the G38 command (e.g. 38.2) for a probing block, or 0
+	probe   float64
+	contact bool
+
+	// dwell is the remaining dwell time in seconds
+	dwell float64
+}
+
+type overrides struct {
+	feed, rapid, spindle int
+}
+
+func (o *overrides) reset() {
+	o.feed, o.rapid, o.spindle = 100, 100, 100
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// override handles an override realtime command, returning false if b is not one.
+func (m *Machine) override(b byte) bool {
+	o := &m.ov
+	switch b {
+	case 0x90:
+		o.feed = 100
+	case 0x91:
+		o.feed += 10
+	case 0x92:
+		o.feed -= 10
+	case 0x93:
+		o.feed++
+	case 0x94:
+		o.feed--
+	case 0x95:
+		o.rapid = 100
+	case 0x96:
+		o.rapid = 50
+	case 0x97:
+		o.rapid = 25
+	case 0x99:
+		o.spindle = 100
+	case 0x9A:
+		o.spindle += 10
+	case 0x9B:
+		o.spindle -= 10
+	case 0x9C:
+		o.spindle++
+	case 0x9D:
+		o.spindle--
+	default:
+		return false
+	}
+	o.feed = clamp(o.feed, 10, 200)
+	o.spindle = clamp(o.spindle, 10, 200)
+
+	// report the change with the next status
+	m.ovCount = 0
+	return true
+}
+
+func sub(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+func add(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+func dist(a, b [3]float64) float64 {
+	d := sub(b, a)
+	return math.Sqrt(d[0]*d[0] + d[1]*d[1] + d[2]*d[2])
+}
+
+// queue adds a block to the planner, starting a cycle if idle.
+func (m *Machine) queue(b block) {
+	m.planner = append(m.planner, b)
+	m.tail = b.target
+	if m.state != stateIdle {
+		return
+	}
+	if b.jog {
+		m.state = stateJog
+	} else {
+		m.state = stateRun
+	}
+}
+
+// rate returns the current speed, in mm/min, for the block starting at the current position.
+func (m *Machine) rate(b *block) float64 {
+	d := dist(m.mpos, b.target)
+	if d == 0 {
+		return 0
+	}
+	max := math.Inf(1)
+	for i := range b.target {
+		u := math.Abs(b.target[i]-m.mpos[i]) / d
+		if u == 0 {
+			continue
+		}
+		max = math.Min(max, m.settings.maxRate(i)/u)
+	}
+	switch {
+	case b.jog:
+		return math.Min(b.feed, max)
+	case b.rapid:
+		return max * float64(m.ov.rapid) / 100
+	}
+	return math.Min(b.feed*float64(m.ov.feed)/100, max)
+}
+
+// move advances the simulation by dt.
+func (m *Machine) move(dt time.Duration) {
+	if m.homing > 0 {
+		m.homing -= dt
+		if m.homing <= 0 {
+			m.homeComplete()
+		}
+		return
+	}
+
+	switch m.state {
+	case stateHold:
+		// deceleration is instant
+		m.state = stateHeld
+		m.feed = 0
+		return
+	case stateRun, stateJog:
+	default:
+		return
+	}
+
+	t := dt.Minutes()
+	for t > 0 && len(m.planner) > 0 {
+		b := &m.planner[0]
+		m.ln = b.line
+		if b.dwell > 0 {
+			m.feed = 0
+			used := math.Min(t, b.dwell/60)
+			b.dwell -= used * 60
+			t -= used
+			if b.dwell > 1e-9 {
+				break
+			}
+			m.planner = m.planner[1:]
+			continue
+		}
+
+		m.feed = m.rate(b)
+		d := dist(m.mpos, b.target)
+		if m.feed == 0 || d <= m.feed*t {
+			m.mpos = b.target
+			if m.feed > 0 {
+				t -= d / m.feed
+			}
+			done := *b
+			m.planner = m.planner[1:]
+			if done.probe != 0 {
+				m.probeComplete(done)
+			}
+			continue
+		}
+
+		f := m.feed * t / d
+		for i := range m.mpos {
+			m.mpos[i] += (b.target[i] - m.mpos[i]) * f
+		}
+		t = 0
+	}
+
+	if len(m.planner) == 0 && (m.state == stateRun || m.state == stateJog) {
+		m.state = stateIdle
+		m.feed = 0
+		m.ln = 0
+	}
+}
+
+// homePos returns the machine position after homing.
+func (m *Machine) homePos() [3]float64 {
+	var p [3]float64
+	pulloff := m.settings[27]
+	for i := range p {
+		if m.settings.homingInvert(i) {
+			p[i] = -m.settings.maxTravel(i) + pulloff
+		} else {
+			p[i] = -pulloff
+		}
+	}
+	return p
+}
+
+// home will start the homing cycle. The response is sent once complete.
+func (m *Machine) home() {
+	m.state = stateHome
+	d := dist(m.mpos, m.homePos())
+	if m.homed {
+		d = math.Max(d, m.settings[27])
+	} else {
+		// position is unknown, assume the worst
+		d = math.Max(d, m.settings.maxTravel(0))
+	}
+	m.homing = time.Duration(d / m.settings[25] * float64(time.Minute))
+	if m.homing <= 0 {
+		m.homing = 1
+	}
+}
+
+func (m *Machine) homeComplete() {
+	m.homing = 0
+	m.mpos = m.homePos()
+	m.tail = m.mpos
+	m.homed = true
+	m.state = stateIdle
+	m.println("ok")
+}
+
+// report writes a status report.
+func (m *Machine) report() {
+	s := "<" + string(m.state)
+
+	wco := m.wco(m.parser)
+	if m.settings.reportMPos() {
+		s += "|MPos:" + fmtPos(m.mpos)
+	} else {
+		s += "|WPos:" + fmtPos(sub(m.mpos, wco))
+	}
+	if m.settings.reportBuf() {
+		s += "|Bf:" + itoa(PlannerSize-len(m.planner)) + "," + itoa(RXBufferSize-len(m.rx))
+	}
+	if m.ln > 0 {
+		s += "|Ln:" + itoa(m.ln)
+	}
+	var spindle float64
+	if m.parser.Spindle != gcode.SpindleOff {
+		spindle = m.parser.SpindleSpeed * float64(m.ov.spindle) / 100
+	}
+	s += "|FS:" + itoa(int(m.feed)) + "," + itoa(int(spindle))
+	if m.stock != nil && m.stock.contains(m.mpos) {
+		s += "|Pn:P"
+	}
+
+	busy := m.state != stateIdle && m.state != stateAlarm && m.state != stateCheck
+	if m.wcoCount > 0 {
+		m.wcoCount--
+	} else {
+		m.wcoCount = 9
+		if busy {
+			m.wcoCount = 29
+		}
+		if m.ovCount == 0 {
+			// Ov is sent with the next report instead
+			m.ovCount = 1
+		}
+		s += "|WCO:" + fmtPos(wco)
+	}
+	if m.ovCount > 0 {
+		m.ovCount--
+	} else {
+		m.ovCount = 9
+		if busy {
+			m.ovCount = 19
+		}
+		s += "|Ov:" + itoa(m.ov.feed) + "," + itoa(m.ov.rapid) + "," + itoa(m.ov.spindle)
+
+		var a string
+		switch m.parser.Spindle {
+		case gcode.SpindleCW:
+			a += "S"
+		case gcode.SpindleCCW:
+			a += "C"
+		}
+		if m.parser.Flood {
+			a += "F"
+		}
+		if m.parser.Mist {
+			a += "M"
+		}
+		if a != "" {
+			s += "|A:" + a
+		}
+	}
+
+	m.println(s + ">")
+}
@@ -0,0 +1,95 @@
+package sim
+
+import "math"
+
+type box struct {
+	min, max [3]float64
+}
+
+func (b *box) contains(p [3]float64) bool {
+	for i := range p {
+		if p[i] < b.min[i] || p[i] > b.max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// intersect returns the fraction of the segment from a to c where it enters and exits the box.
+// ok is false if the segment's line never touches it.
+func (b *box) intersect(a, c [3]float64) (enter, exit float64, ok bool) {
+	enter, exit = math.Inf(-1), math.Inf(1)
+	for i := range a {
+		d := c[i] - a[i]
+		if d == 0 {
+			if a[i] < b.min[i] || a[i] > b.max[i] {
+				return 0, 0, false
+			}
+			continue
+		}
+		t1 := (b.min[i] - a[i]) / d
+		t2 := (b.max[i] - a[i]) / d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		enter = math.Max(enter, t1)
+		exit = math.Min(exit, t2)
+	}
+	return enter, exit, enter <= exit
+}
+
+func isProbe(motion float64) bool {
+	return motion == 38.2 || motion == 38.3 || motion == 38.4 || motion == 38.5
+}
+
+// probe will start a probing cycle toward target. The response is sent once complete.
+func (m *Machine) probe(motion float64, target [3]float64, feed float64, line int) int {
+	away := motion == 38.4 || motion == 38.5
+	start := m.tail
+	triggered := m.stock != nil && m.stock.contains(start)
+	if triggered != away {
+		// unlike other alarms, this does not reset the controller
+		m.state = stateAlarm
+		m.println("ALARM:" + itoa(alarmProbeInit))
+		return 0
+	}
+
+	b := block{target: target, feed: feed, line: line, probe: motion}
+	if m.stock != nil {
+		enter, exit, ok := m.stock.intersect(start, target)
+		t := enter
+		if away {
+			t = exit
+		}
+		if ok && t >= 0 && t <= 1 {
+			b.contact = true
+			for i := range b.target {
+				b.target[i] = start[i] + (target[i]-start[i])*t
+			}
+		}
+	}
+
+	m.probing = true
+	m.queue(b)
+	return -1
+}
+
+func (m *Machine) probeComplete(b block) {
+	m.probing = false
+	m.prb = m.mpos
+	m.prbOK = b.contact
+	if !b.contact && (b.probe == 38.2 || b.probe == 38.4) {
+		m.planner = m.planner[:0]
+		m.state = stateAlarm
+		m.println("ALARM:" + itoa(alarmProbeFail))
+	}
+	m.println("[PRB:" + fmtPos(m.prb) + ":" + boolString(m.prbOK) + "]")
+	m.println("ok")
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
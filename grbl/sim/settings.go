@@ -0,0 +1,85 @@
+package sim
+
+import (
+	"sort"
+	"strconv"
+)
+
+// settings holds the `$` settings of the Machine, keyed by number.
+type settings map[int]float64
+
+// intSettings are reported without decimals.
+var intSettings = map[int]bool{
+	0: true, 1: true, 2: true, 3: true, 4: true, 5: true, 6: true, 10: true, 13: true,
+	20: true, 21: true, 22: true, 23: true, 26: true, 30: true, 31: true, 32: true,
+}
+
+// defaultSettings are the Grbl 1.1 defaults.
+func defaultSettings() settings {
+	return settings{
+		0: 10, 1: 25, 2: 0, 3: 0, 4: 0, 5: 0, 6: 0,
+		10: 1, 11: 0.010, 12: 0.002, 13: 0,
+		20: 0, 21: 0, 22: 0, 23: 0, 24: 25, 25: 500, 26: 250, 27: 1,
+		30: 1000, 31: 0, 32: 0,
+		100: 250, 101: 250, 102: 250,
+		110: 500, 111: 500, 112: 500,
+		120: 10, 121: 10, 122: 10,
+		130: 200, 131: 200, 132: 200,
+	}
+}
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+func (s settings) format(n int) string {
+	if intSettings[n] {
+		return "$" + itoa(n) + "=" + itoa(int(s[n]))
+	}
+	return "$" + itoa(n) + "=" + ftoa(s[n])
+}
+
+func (s settings) keys() []int {
+	keys := make([]int, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func (s settings) homing() bool     { return s[22] == 1 }
+func (s settings) softLimits() bool { return s[20] == 1 }
+func (s settings) reportMPos() bool { return int(s[10])&1 != 0 }
+func (s settings) reportBuf() bool  { return int(s[10])&2 != 0 }
+func (s settings) maxRate(axis int) float64 {
+	return s[110+axis]
+}
+func (s settings) maxTravel(axis int) float64 {
+	return s[130+axis]
+}
+func (s settings) homingInvert(axis int) bool {
+	return int(s[23])&(1<<uint(axis)) != 0
+}
+
+// set will update a setting, returning a Grbl error code on failure.
+func (s settings) set(n int, v float64) int {
+	if _, ok := s[n]; !ok {
+		return errInvalidStatement
+	}
+	if v < 0 {
+		return errNegativeValue
+	}
+	if n == 20 && v == 1 && !s.homing() {
+		return errSoftLimitNoHoming
+	}
+	if intSettings[n] {
+		v = float64(int(v))
+	}
+	s[n] = v
+	return 0
+}
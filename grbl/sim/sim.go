@@ -0,0 +1,320 @@
+// Package sim provides a virtual Grbl 1.1 controller for testing and development without hardware.
+//
+// A Machine speaks the same serial protocol as a real controller, including character-counting
+// flow control via a limited RX buffer, realtime commands, status reports, settings, check mode,
+// jogging, homing, probing and alarms. Motion is simulated at constant velocity (no acceleration).
+package sim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+const (
+	// RXBufferSize is the size of the serial receive buffer, in bytes.
+	RXBufferSize = 128
+
+	// PlannerSize is the number of motion blocks the planner can hold.
+	PlannerSize = 15
+
+	// Version is reported in the startup banner and build info.
+	Version = "1.1f"
+
+	lineBufferSize = 80
+	tickInterval   = 10 * time.Millisecond
+)
+
+// ErrOverflow is returned by Write if the data would exceed the RX buffer. Real hardware
+// would silently drop the data.
+var ErrOverflow = errors.New("serial RX buffer overflow")
+
+// ErrClosed is returned when writing to a closed Machine.
+var ErrClosed = errors.New("machine closed")
+
+type state string
+
+const (
+	stateIdle  state = "Idle"
+	stateRun   state = "Run"
+	stateHold  state = "Hold:1"
+	stateHeld  state = "Hold:0"
+	stateJog   state = "Jog"
+	stateAlarm state = "Alarm"
+	stateCheck state = "Check"
+	stateHome  state = "Home"
+)
+
+// Machine is a virtual Grbl controller. It implements io.ReadWriteCloser.
+type Machine struct {
+	mx   sync.Mutex
+	cond *sync.Cond
+
+	out    bytes.Buffer
+	rx     []byte
+	closed bool
+	speed  float64
+
+	settings settings
+
+	state  state
+	homed  bool
+
+	// critical is set by a critical alarm (e.g. soft limit), which ignores everything but status
+	// reports until a soft-reset.
+	critical bool
+	mpos   [3]float64
+	parser gcode.State
+
+	// tail is the machine position at the end of the planner (i.e. where the next block starts).
+	tail    [3]float64
+	planner []block
+	ln      int
+	feed    float64
+
+	homing   time.Duration
+	probing  bool
+	checkOff bool
+
+	wcs      [6][3]float64
+	g28, g30 [3]float64
+	prb      [3]float64
+	prbOK    bool
+
+	// stock is the region, in machine coordinates, that triggers the probe.
+	stock *box
+
+	ov       overrides
+	wcoCount int
+	ovCount  int
+
+	closeCh chan struct{}
+}
+
+// NewMachine will create and power-on a new Machine with default settings.
+func NewMachine() *Machine {
+	m := &Machine{
+		speed:    1,
+		settings: defaultSettings(),
+		closeCh:  make(chan struct{}),
+	}
+	m.cond = sync.NewCond(&m.mx)
+	m.mx.Lock()
+	if m.settings.homing() {
+		// homing lock on power-up
+		m.state = stateAlarm
+	}
+	m.reset()
+	m.mx.Unlock()
+	go m.loop()
+	return m
+}
+
+// SetSpeed will set the simulation speed as a multiple of real time.
+func (m *Machine) SetSpeed(s float64) {
+	m.mx.Lock()
+	m.speed = s
+	m.mx.Unlock()
+}
+
+// SetStock will place a rectangular block of stock (e.g. with a touch plate on top) between
+// the machine coordinates min and max. The probe input is triggered while the tool is inside it.
+func (m *Machine) SetStock(min, max [3]float64) {
+	m.mx.Lock()
+	m.stock = &box{min: min, max: max}
+	m.mx.Unlock()
+}
+
+// Read will block until response data is available from the Machine.
+func (m *Machine) Read(p []byte) (int, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	for m.out.Len() == 0 && !m.closed {
+		m.cond.Wait()
+	}
+	if m.out.Len() == 0 {
+		return 0, io.EOF
+	}
+	return m.out.Read(p)
+}
+
+// Write sends data to the Machine. Realtime commands are processed immediately, all
+// other data is placed in the RX buffer (or discarded after a critical alarm).
+func (m *Machine) Write(p []byte) (int, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if m.closed {
+		return 0, ErrClosed
+	}
+	for i, b := range p {
+		if m.realtime(b) || m.critical {
+			continue
+		}
+		if len(m.rx) >= RXBufferSize {
+			m.cond.Broadcast()
+			return i, ErrOverflow
+		}
+		m.rx = append(m.rx, b)
+	}
+	m.processLines()
+	m.cond.Broadcast()
+	return len(p), nil
+}
+
+// Close will shut down the Machine. Pending reads will return io.EOF.
+func (m *Machine) Close() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	close(m.closeCh)
+	m.cond.Broadcast()
+	return nil
+}
+
+func (m *Machine) printf(s string) {
+	m.out.WriteString(s)
+	m.cond.Broadcast()
+}
+func (m *Machine) println(s string) {
+	m.printf(s + "\r\n")
+}
+
+func (m *Machine) loop() {
+	t := time.NewTicker(tickInterval)
+	defer t.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-m.closeCh:
+			return
+		case now := <-t.C:
+			m.mx.Lock()
+			m.tick(time.Duration(float64(now.Sub(last)) * m.speed))
+			m.mx.Unlock()
+			last = now
+		}
+	}
+}
+
+func (m *Machine) tick(dt time.Duration) {
+	m.move(dt)
+	m.processLines()
+}
+
+// processLines will execute complete lines from the RX buffer while there is room in the planner.
+func (m *Machine) processLines() {
+	for !m.busy() {
+		idx := bytes.IndexByte(m.rx, '\n')
+		if idx == -1 {
+			return
+		}
+		line := string(bytes.TrimRight(m.rx[:idx], "\r"))
+		m.rx = m.rx[idx+1:]
+		if len(line) == 0 {
+			continue
+		}
+		if len(line) >= lineBufferSize {
+			m.println("error:11")
+			continue
+		}
+
+		// a negative code means the response is deferred or not sent
+		code := m.execLine(line)
+		if code == 0 {
+			m.println("ok")
+		} else if code > 0 {
+			m.println("error:" + itoa(code))
+		}
+		if m.checkOff {
+			m.checkOff = false
+			m.reset()
+		}
+	}
+}
+
+// busy returns true if lines from the RX buffer cannot be processed.
+func (m *Machine) busy() bool {
+	return m.homing > 0 || m.probing || len(m.planner) >= PlannerSize
+}
+
+// reset performs a soft-reset, as with the 0x18 realtime command.
+func (m *Machine) reset() {
+	inMotion := len(m.planner) > 0 && (m.state == stateRun || m.state == stateJog || m.state == stateHold)
+	homing := m.homing > 0
+	m.rx = m.rx[:0]
+	m.planner = m.planner[:0]
+	m.homing = 0
+	m.probing = false
+	m.critical = false
+	m.ln = 0
+	m.feed = 0
+
+	in := gcode.NewInterpreter()
+	m.parser = in.State()
+	m.parser.Motion = gcode.MotionRapid
+	m.tail = m.mpos
+	m.ov.reset()
+	m.wcoCount = 0
+	m.ovCount = 0
+
+	switch {
+	case homing:
+		m.state = stateAlarm
+		m.println("ALARM:" + itoa(alarmHomingReset))
+	case inMotion:
+		// position is lost
+		m.homed = false
+		m.state = stateAlarm
+		m.println("ALARM:" + itoa(alarmAbortCycle))
+	case m.state == stateAlarm:
+	default:
+		m.state = stateIdle
+	}
+
+	m.printf("\r\nGrbl " + Version + " ['$' for help]\r\n")
+	if m.state == stateAlarm {
+		m.println("[MSG:'$H'|'$X' to unlock]")
+	}
+}
+
+// realtime handles realtime command bytes, returning false if b is not one.
+func (m *Machine) realtime(b byte) bool {
+	switch b {
+	case '?':
+		m.report()
+	case '!':
+		switch m.state {
+		case stateRun:
+			m.state = stateHold
+		case stateJog:
+			m.jogCancel()
+		}
+	case '~':
+		if m.state == stateHold || m.state == stateHeld {
+			m.state = stateRun
+		}
+	case 0x18:
+		m.reset()
+	case 0x85:
+		m.jogCancel()
+	default:
+		return m.override(b) || b > 0x7f
+	}
+	return true
+}
+
+func (m *Machine) jogCancel() {
+	if m.state != stateJog {
+		return
+	}
+	m.planner = m.planner[:0]
+	m.tail = m.mpos
+	m.state = stateIdle
+}
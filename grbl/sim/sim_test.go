@@ -0,0 +1,190 @@
+package sim
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConn struct {
+	t *testing.T
+	m *Machine
+	r *bufio.Reader
+}
+
+func newTestConn(t *testing.T) *testConn {
+	m := NewMachine()
+	m.SetSpeed(100)
+	c := &testConn{t: t, m: m, r: bufio.NewReader(m)}
+	c.expect("Grbl 1.1f ['$' for help]")
+	return c
+}
+
+func (c *testConn) send(s string) {
+	_, err := c.m.Write([]byte(s))
+	if err != nil {
+		c.t.Fatalf("write %q: %v", s, err)
+	}
+}
+
+func (c *testConn) readLine() string {
+	for {
+		s, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("read: %v", err)
+		}
+		s = strings.TrimSpace(s)
+		if s != "" {
+			return s
+		}
+	}
+}
+
+func (c *testConn) expect(lines ...string) {
+	for _, exp := range lines {
+		s := c.readLine()
+		if s != exp {
+			c.t.Fatalf("got %q; want %q", s, exp)
+		}
+	}
+}
+
+// waitIdle will poll status until the machine is idle, returning the last report.
+func (c *testConn) waitIdle() string {
+	for i := 0; i < 1000; i++ {
+		c.send("?")
+		s := c.readLine()
+		if strings.HasPrefix(s, "<Idle|") {
+			return s
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.t.Fatal("timeout waiting for idle")
+	return ""
+}
+
+func TestMachine_Status(t *testing.T) {
+	c := newTestConn(t)
+	defer c.m.Close()
+
+	c.send("?")
+	c.expect("<Idle|MPos:0.000,0.000,0.000|FS:0,0|WCO:0.000,0.000,0.000>")
+	c.send("?")
+	c.expect("<Idle|MPos:0.000,0.000,0.000|FS:0,0|Ov:100,100,100>")
+
+	c.send("$10=3\nG10 L2 P1 X1 Y2 Z3\nM3 S1000\n")
+	c.expect("ok", "ok", "ok")
+	c.send("?")
+	c.expect("<Idle|MPos:0.000,0.000,0.000|Bf:15,128|FS:0,1000|WCO:1.000,2.000,3.000>")
+	c.send("?")
+	c.expect("<Idle|MPos:0.000,0.000,0.000|Bf:15,128|FS:0,1000|Ov:100,100,100|A:S>")
+}
+
+func TestMachine_Motion(t *testing.T) {
+	c := newTestConn(t)
+	defer c.m.Close()
+
+	c.send("G1 X-1\n")
+	c.expect("error:22")
+
+	c.send("G21 G91\nG1 X-10 Y-5 F600\nG0 Z-1\nN5 G2 X-2 Y0 I-1 J0\n")
+	c.expect("ok", "ok", "ok", "ok")
+
+	s := c.waitIdle()
+	if !strings.HasPrefix(s, "<Idle|MPos:-12.000,-5.000,-1.000|") {
+		t.Errorf("status = %s; want MPos:-12,-5,-1", s)
+	}
+
+	c.send("$J=G91 Z1\n")
+	c.expect("error:16")
+}
+
+func TestMachine_CheckMode(t *testing.T) {
+	c := newTestConn(t)
+	defer c.m.Close()
+
+	c.send("$C\n")
+	c.expect("[MSG:Enabled]", "ok")
+	c.send("G0 X10\nG2 X1\nG41\n")
+	c.expect("ok", "error:35", "error:20")
+	c.send("?")
+	c.expect("<Check|MPos:0.000,0.000,0.000|FS:0,0|WCO:0.000,0.000,0.000>")
+	c.send("$C\n")
+	c.expect("[MSG:Disabled]", "ok", "Grbl 1.1f ['$' for help]")
+}
+
+func TestMachine_HomingSoftLimits(t *testing.T) {
+	c := newTestConn(t)
+	defer c.m.Close()
+
+	c.send("$20=1\n")
+	c.expect("error:10")
+	c.send("$22=1\n$20=1\n$H\n")
+	c.expect("ok", "ok", "ok")
+
+	c.send("?")
+	c.expect("<Idle|MPos:-1.000,-1.000,-1.000|FS:0,0|WCO:0.000,0.000,0.000>")
+
+	c.send("$J=G91 X10 F1000\n")
+	c.expect("error:15")
+
+	c.send("G53 G0 X-250\n")
+	c.expect("ALARM:2", "[MSG:Reset to continue]")
+	// ignored until reset
+	c.send("G0 X-10\n$X\n?")
+	c.expect("<Alarm|MPos:-1.000,-1.000,-1.000|FS:0,0|Ov:100,100,100>")
+	c.send("\x18")
+	c.expect("Grbl 1.1f ['$' for help]", "[MSG:'$H'|'$X' to unlock]")
+	c.send("G0 X-10\n")
+	c.expect("error:9")
+	c.send("$X\n")
+	c.expect("[MSG:Caution: Unlocked]", "ok")
+
+	// homing is required for soft limits again
+	c.send("G53 G0 X-250\n")
+	c.expect("ok")
+}
+
+func TestMachine_Reset(t *testing.T) {
+	c := newTestConn(t)
+	defer c.m.Close()
+
+	c.m.SetSpeed(1)
+	c.send("G1 X-100 F100\n")
+	c.expect("ok")
+	c.send("!")
+	c.send("\x18")
+	c.expect("ALARM:3", "Grbl 1.1f ['$' for help]", "[MSG:'$H'|'$X' to unlock]")
+}
+
+func TestMachine_Overflow(t *testing.T) {
+	m := NewMachine()
+	defer m.Close()
+
+	n, err := m.Write([]byte(strings.Repeat("G0", 100)))
+	if err != ErrOverflow {
+		t.Errorf("err = %v; want ErrOverflow", err)
+	}
+	if n != RXBufferSize {
+		t.Errorf("n = %d; want %d", n, RXBufferSize)
+	}
+}
+
+func TestMachine_Probe(t *testing.T) {
+	c := newTestConn(t)
+	defer c.m.Close()
+	c.m.SetStock([3]float64{-100, -100, -200}, [3]float64{0, 0, -20})
+
+	c.send("G21 G91 G38.2 Z-50 F500\n")
+	c.expect("[PRB:0.000,0.000,-20.000:1]", "ok")
+	c.send("G38.4 Z5\n")
+	c.expect("[PRB:0.000,0.000,-20.000:1]", "ok")
+	c.send("G0 Z1\nG38.3 X1\n")
+	c.expect("ok", "[PRB:1.000,0.000,-19.000:0]", "ok")
+
+	c.send("G38.2 X1\n")
+	c.expect("ALARM:5", "[PRB:2.000,0.000,-19.000:0]", "ok")
+	c.send("$X\nG0 X-5 Z-5\nG38.2 Z-1\n")
+	c.expect("[MSG:Caution: Unlocked]", "ok", "ok", "ALARM:4", "ok")
+}
@@ -39,6 +39,9 @@ const (
 type Status struct {
 	State State
 
+	// Alarm is the last alarm received while in the alarm state, if known.
+	Alarm Alarm
+
 	Fields []string
 
 	MPos []float64
@@ -47,6 +50,9 @@ type Status struct {
 	BlockBufferAvailable  int
 	SerialBufferAvailable int
 
+	// Buffers is the use of Grbl's buffers, updated with each status report.
+	Buffers Buffers
+
 	Line int
 
 	FeedSpeed    float64
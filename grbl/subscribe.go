@@ -0,0 +1,206 @@
+package grbl
+
+import (
+	"strings"
+	"sync"
+)
+
+// eventBuffer is the number of events (push messages, alarms and feedback messages) held for
+// each subscriber before the oldest are dropped.
+const eventBuffer = 64
+
+// subscribers holds the channels of each subscription. Sends never block: status and settings
+// subscribers only hold the latest value, and event subscribers drop the oldest event when full.
+type subscribers struct {
+	mx   sync.Mutex
+	next int
+
+	status   map[int]chan Status
+	settings map[int]chan Settings
+	push     map[int]chan []byte
+	alarms   map[int]chan Alarm
+	messages map[int]chan string
+}
+
+func newSubscribers() *subscribers {
+	return &subscribers{
+		status:   make(map[int]chan Status),
+		settings: make(map[int]chan Settings),
+		push:     make(map[int]chan []byte),
+		alarms:   make(map[int]chan Alarm),
+		messages: make(map[int]chan string),
+	}
+}
+
+// add will register a subscription with register, which is called with the lock held, and return
+// the function to remove it.
+func (sub *subscribers) add(register func(id int), remove func(id int)) func() {
+	sub.mx.Lock()
+	id := sub.next
+	sub.next++
+	register(id)
+	sub.mx.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sub.mx.Lock()
+			remove(id)
+			sub.mx.Unlock()
+		})
+	}
+}
+
+// The send functions replace the oldest value if ch is full. They must only be called with the lock
+// held, so there is a single sender for each channel.
+
+func sendStatus(ch chan Status, s Status) {
+	select {
+	case ch <- s:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- s
+}
+func sendSettings(ch chan Settings, s Settings) {
+	select {
+	case ch <- s:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- s
+}
+func sendPush(ch chan []byte, data []byte) {
+	select {
+	case ch <- data:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- data
+}
+func sendAlarm(ch chan Alarm, a Alarm) {
+	select {
+	case ch <- a:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- a
+}
+func sendMessage(ch chan string, msg string) {
+	select {
+	case ch <- msg:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- msg
+}
+
+// Subscribe returns a channel of status updates, holding only the latest, and a function to
+// unsubscribe (which closes the channel).
+func (g *Grbl) Subscribe() (<-chan Status, func()) {
+	ch := make(chan Status, 1)
+	return ch, g.subs.add(
+		func(id int) { g.subs.status[id] = ch },
+		func(id int) { delete(g.subs.status, id); close(ch) },
+	)
+}
+
+// SubscribeSettings returns a channel of settings, holding only the latest, sent each time they
+// are read from Grbl (e.g. after a reset), and a function to unsubscribe.
+func (g *Grbl) SubscribeSettings() (<-chan Settings, func()) {
+	ch := make(chan Settings, 1)
+	return ch, g.subs.add(
+		func(id int) { g.subs.settings[id] = ch },
+		func(id int) { delete(g.subs.settings, id); close(ch) },
+	)
+}
+
+// SubscribePush returns a channel of every push message (i.e. anything other than a response to a
+// command, including status reports) from Grbl, and a function to unsubscribe. Messages are shared
+// between subscribers and must not be modified.
+func (g *Grbl) SubscribePush() (<-chan []byte, func()) {
+	ch := make(chan []byte, eventBuffer)
+	return ch, g.subs.add(
+		func(id int) { g.subs.push[id] = ch },
+		func(id int) { delete(g.subs.push, id); close(ch) },
+	)
+}
+
+// SubscribeAlarms returns a channel of alarms raised by Grbl, and a function to unsubscribe.
+func (g *Grbl) SubscribeAlarms() (<-chan Alarm, func()) {
+	ch := make(chan Alarm, eventBuffer)
+	return ch, g.subs.add(
+		func(id int) { g.subs.alarms[id] = ch },
+		func(id int) { delete(g.subs.alarms, id); close(ch) },
+	)
+}
+
+// SubscribeMessages returns a channel of feedback messages from Grbl (e.g. "Caution:Unlocked" for
+// `[MSG:Caution:Unlocked]`), and a function to unsubscribe.
+func (g *Grbl) SubscribeMessages() (<-chan string, func()) {
+	ch := make(chan string, eventBuffer)
+	return ch, g.subs.add(
+		func(id int) { g.subs.messages[id] = ch },
+		func(id int) { delete(g.subs.messages, id); close(ch) },
+	)
+}
+
+// publishStatus will send the current status to all subscribers, and to Status().
+func (g *Grbl) publishStatus() {
+	g.subs.mx.Lock()
+	defer g.subs.mx.Unlock()
+	sendStatus(g.statusCh, g.s)
+	for _, ch := range g.subs.status {
+		sendStatus(ch, g.s)
+	}
+}
+
+// publishSettings will send s to all settings subscribers, and to Settings().
+func (g *Grbl) publishSettings(s Settings) {
+	g.subs.mx.Lock()
+	defer g.subs.mx.Unlock()
+	sendSettings(g.settingsCh, s)
+	for _, ch := range g.subs.settings {
+		sendSettings(ch, s)
+	}
+}
+
+// publishPush will send a push message to subscribers, along with any alarm or feedback message it contains.
+func (g *Grbl) publishPush(data []byte) {
+	g.subs.mx.Lock()
+	defer g.subs.mx.Unlock()
+	for _, ch := range g.subs.push {
+		sendPush(ch, data)
+	}
+	if a, ok := parseAlarm(data); ok {
+		for _, ch := range g.subs.alarms {
+			sendAlarm(ch, a)
+		}
+	}
+	if s := string(data); strings.HasPrefix(s, "[MSG:") && strings.HasSuffix(s, "]") {
+		msg := s[5 : len(s)-1]
+		for _, ch := range g.subs.messages {
+			sendMessage(ch, msg)
+		}
+	}
+}
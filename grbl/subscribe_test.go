@@ -0,0 +1,66 @@
+package grbl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl/sim"
+)
+
+func TestGrbl_Subscribe(t *testing.T) {
+	m := sim.NewMachine()
+	defer m.Close()
+	g := NewGrbl(m)
+	<-g.Settings()
+
+	// never read, which must not block anything else
+	_, stopIdle := g.Subscribe()
+	defer stopIdle()
+	status, stopStatus := g.Subscribe()
+	alarms, stopAlarms := g.SubscribeAlarms()
+	defer stopAlarms()
+	msgs, stopMsgs := g.SubscribeMessages()
+	defer stopMsgs()
+
+	for i := 0; i < 3; i++ {
+		g.Status()
+	}
+	select {
+	case s := <-status:
+		if s.State == StateUnknown {
+			t.Errorf("state = %s; want known", s.State)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no status")
+	}
+
+	// probing away while not in contact
+	g.ExecLine(gcode.Line{{Type: 'G', Value: 38.4}, {Type: 'Z', Value: 1}, {Type: 'F', Value: 100}})
+	select {
+	case a := <-alarms:
+		if a != AlarmProbeInitial {
+			t.Errorf("alarm = %d; want %d", a, AlarmProbeInitial)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alarm")
+	}
+
+	g.Unlock()
+	select {
+	case msg := <-msgs:
+		if msg != "Caution:Unlocked" {
+			t.Errorf("message = %q; want Caution:Unlocked", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+	}
+
+	stopStatus()
+	stopStatus()
+	for range status {
+	}
+	if _, err := g.Parameters(); err != nil {
+		t.Errorf("Parameters: %v", err)
+	}
+}
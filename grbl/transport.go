@@ -0,0 +1,236 @@
+package grbl
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrConnectionLost is returned for commands that were pending (or sent) when the connection
+// to Grbl was lost.
+var ErrConnectionLost = errors.New("connection lost")
+
+// ConnectionState is reported by a Transport when the connection is lost or re-established.
+type ConnectionState int
+
+const (
+	ConnectionSuccess ConnectionState = iota
+	ConnectionLost
+	ConnectionFailed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionSuccess:
+		return "Connected"
+	case ConnectionLost:
+		return "Connection lost"
+	case ConnectionFailed:
+		return "Connection failed"
+	}
+	return "Unknown"
+}
+
+const (
+	minReconnectDelay = 250 * time.Millisecond
+	maxReconnectDelay = 10 * time.Second
+
+	// handshakeTimeout is the time to wait for the startup banner after reconnecting.
+	handshakeTimeout = 5 * time.Second
+)
+
+// DialFunc opens a connection to Grbl (e.g. a serial port or TCP connection).
+type DialFunc func() (io.ReadWriteCloser, error)
+
+// Transport is a connection to Grbl that will reconnect, with backoff, when an I/O error occurs.
+//
+// The read or write that encounters the error returns ErrConnectionLost, after which writes
+// fail with ErrConnectionLost and reads block until reconnected.
+type Transport struct {
+	dial DialFunc
+	l    *log.Logger
+
+	mx     sync.Mutex
+	cond   *sync.Cond
+	rwc    io.ReadWriteCloser
+	closed bool
+
+	stateCh chan ConnectionState
+}
+
+// DialTransport will make the initial connection using dial, returning an error if it fails.
+func DialTransport(dial DialFunc) (*Transport, error) {
+	rwc, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	t := &Transport{
+		dial:    dial,
+		l:       log.New(ioutil.Discard, "", 0),
+		rwc:     rwc,
+		stateCh: make(chan ConnectionState),
+	}
+	t.cond = sync.NewCond(&t.mx)
+	return t, nil
+}
+
+// SetLogger will set the logger used to report reconnect failures.
+func (t *Transport) SetLogger(l *log.Logger) {
+	t.mx.Lock()
+	t.l = l
+	t.mx.Unlock()
+}
+
+// Connection returns a channel of connection state changes. It must be read for reconnection
+// to continue after a state is reported.
+func (t *Transport) Connection() chan ConnectionState {
+	return t.stateCh
+}
+
+// conn will wait for a connection, returning nil if the Transport is closed.
+func (t *Transport) conn() io.ReadWriteCloser {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	for t.rwc == nil && !t.closed {
+		t.cond.Wait()
+	}
+	if t.closed {
+		return nil
+	}
+	return t.rwc
+}
+
+func (t *Transport) Read(p []byte) (int, error) {
+	for {
+		rwc := t.conn()
+		if rwc == nil {
+			return 0, io.ErrClosedPipe
+		}
+		n, err := rwc.Read(p)
+		if err == nil {
+			return n, nil
+		}
+		if !t.lost(rwc, err) {
+			// already reported by a write, or closed
+			continue
+		}
+		if n > 0 {
+			return n, nil
+		}
+		return 0, ErrConnectionLost
+	}
+}
+
+func (t *Transport) Write(p []byte) (int, error) {
+	t.mx.Lock()
+	rwc, closed := t.rwc, t.closed
+	t.mx.Unlock()
+	if closed {
+		return 0, io.ErrClosedPipe
+	}
+	if rwc == nil {
+		return 0, ErrConnectionLost
+	}
+	n, err := rwc.Write(p)
+	if err != nil {
+		t.lost(rwc, err)
+		return n, ErrConnectionLost
+	}
+	return n, nil
+}
+
+// lost will close rwc and begin reconnecting, returning false if it was already done.
+func (t *Transport) lost(rwc io.ReadWriteCloser, err error) bool {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.rwc != rwc || t.closed {
+		return false
+	}
+	t.l.Println("connection lost:", err)
+	t.rwc = nil
+	rwc.Close()
+	go t.reconnect()
+	return true
+}
+
+func (t *Transport) reconnect() {
+	t.stateCh <- ConnectionLost
+	delay := minReconnectDelay
+	for {
+		time.Sleep(delay)
+		t.mx.Lock()
+		closed := t.closed
+		t.mx.Unlock()
+		if closed {
+			return
+		}
+
+		rwc, err := t.dial()
+		if err != nil {
+			t.l.Println("reconnect:", err)
+			t.stateCh <- ConnectionFailed
+			delay *= 2
+			if delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+			continue
+		}
+
+		t.mx.Lock()
+		if t.closed {
+			t.mx.Unlock()
+			rwc.Close()
+			return
+		}
+		t.rwc = rwc
+		t.cond.Broadcast()
+		t.mx.Unlock()
+		t.stateCh <- ConnectionSuccess
+		return
+	}
+}
+
+// Close will close the current connection and stop reconnecting.
+func (t *Transport) Close() error {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	t.cond.Broadcast()
+	if t.rwc == nil {
+		return nil
+	}
+	return t.rwc.Close()
+}
+
+// watchTransport will repeat the handshake each time t reconnects, and report the connection state.
+func (g *Grbl) watchTransport(t *Transport) {
+	for st := range t.Connection() {
+		switch st {
+		case ConnectionLost:
+			g.sync(func() {
+				g.s.State = StateUnknown
+				g.s.Alarm = 0
+				g.publishStatus()
+			})
+		case ConnectionSuccess:
+			_, err := g.Connect(handshakeTimeout)
+			if err != nil {
+				g.l.Println("handshake:", err)
+				st = ConnectionFailed
+			}
+		}
+		g.connCh <- st
+	}
+}
+
+// Connection returns a channel of connection state changes, when using a Transport. A reconnect is
+// reported after the handshake is complete.
+func (g *Grbl) Connection() chan ConnectionState {
+	return g.connCh
+}
@@ -0,0 +1,85 @@
+package grbl
+
+import (
+	"bufio"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeConn is one end of a connection, the other end being read with in and written with out.
+type fakeConn struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+
+	in  *bufio.Reader
+	out *io.PipeWriter
+}
+
+func newFakeConn() *fakeConn {
+	r, out := io.Pipe()
+	in, w := io.Pipe()
+	return &fakeConn{r: r, w: w, in: bufio.NewReader(in), out: out}
+}
+
+func (c *fakeConn) Read(p []byte) (int, error)  { return c.r.Read(p) }
+func (c *fakeConn) Write(p []byte) (int, error) { return c.w.Write(p) }
+func (c *fakeConn) Close() error {
+	c.r.Close()
+	return c.w.Close()
+}
+
+func TestTransport_Reconnect(t *testing.T) {
+	conns := make(chan *fakeConn, 2)
+	conns <- newFakeConn()
+	conns <- newFakeConn()
+	dialed := make(chan *fakeConn, 2)
+	tr, err := DialTransport(func() (io.ReadWriteCloser, error) {
+		c := <-conns
+		dialed <- c
+		return c, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	c := NewClient(tr, ModeCharacterCount)
+
+	first := <-dialed
+	resp := c.Execute([]byte("G0X1\n"))
+	if l, _ := first.in.ReadString('\n'); l != "G0X1\n" {
+		t.Fatalf("sent %q; want G0X1", l)
+	}
+	// unplugged
+	first.out.CloseWithError(io.ErrUnexpectedEOF)
+
+	select {
+	case r := <-resp:
+		if r.Err != ErrConnectionLost {
+			t.Errorf("err = %v; want ErrConnectionLost", r.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no response after connection lost")
+	}
+	if st := <-tr.Connection(); st != ConnectionLost {
+		t.Errorf("state = %s; want %s", st, ConnectionLost)
+	}
+	if st := <-tr.Connection(); st != ConnectionSuccess {
+		t.Errorf("state = %s; want %s", st, ConnectionSuccess)
+	}
+
+	second := <-dialed
+	resp = c.Execute([]byte("G0X2\n"))
+	if l, _ := second.in.ReadString('\n'); l != "G0X2\n" {
+		t.Fatalf("sent %q; want G0X2", l)
+	}
+	io.WriteString(second.out, "ok\r\n")
+	select {
+	case r := <-resp:
+		if r.Err != nil {
+			t.Errorf("err = %v; want nil", r.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no response after reconnect")
+	}
+}
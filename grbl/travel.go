@@ -0,0 +1,94 @@
+package grbl
+
+import (
+	"fmt"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+// travelTolerance allows for rounding of positions, in mm.
+const travelTolerance = 0.001
+
+// TravelLimits returns the range of machine coordinates (in mm) enforced by soft limits. Grbl
+// places machine zero at the home switch of each axis homed in the positive direction, and
+// max travel from it otherwise, so all positions are negative.
+//
+// ok is false if the limits are not known (e.g. homing is disabled, or the settings have not
+// been read).
+func (s Settings) TravelLimits() (min, max [3]float64, ok bool) {
+	travel := [3]Distance{s.MaxTravel.X, s.MaxTravel.Y, s.MaxTravel.Z}
+	for i, t := range travel {
+		if t <= 0 {
+			return min, max, false
+		}
+		min[i] = -t.Millimeters()
+	}
+	return min, max, s.Homing
+}
+
+// homeEnd returns true if the end of an axis in the given direction (1 for positive) is where
+// the home switch is.
+func (s Settings) homeEnd(axis int, dir float64) bool {
+	inv := [3]bool{s.HomingDirectionInvert.X, s.HomingDirectionInvert.Y, s.HomingDirectionInvert.Z}
+	return inv[axis] == (dir < 0)
+}
+
+// TravelError is a line of a program that would move past the travel limits of the machine.
+type TravelError struct {
+	// Line is the index of the line.
+	Line int
+	Axis byte
+
+	// Position is the machine position that would be reached, and Limit the one it exceeds, in mm.
+	Position float64
+	Limit    float64
+
+	// Home is set if the limit is at the home switch, rather than max travel away from it.
+	Home bool
+}
+
+func (e TravelError) Error() string {
+	end := "max travel"
+	if e.Home {
+		end = "home"
+	}
+	return fmt.Sprintf("line %d: %c%.3f is past the %c %s limit at %.3f", e.Line+1, e.Axis, e.Position, e.Axis, end, e.Limit)
+}
+
+// CheckTravel will return a TravelError for each axis of each line of a program that would move past
+// the travel limits of the machine, when run with the work coordinate offset wco (in mm). Changes
+// to the work coordinate system made by the program itself are not accounted for.
+//
+// No errors are returned if the travel limits are not known.
+func CheckTravel(s Settings, wco [3]float64, lines []gcode.Line) ([]TravelError, error) {
+	min, max, ok := s.TravelLimits()
+	if !ok {
+		return nil, nil
+	}
+	ext, err := gcode.Extents(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []TravelError
+	for n, e := range ext {
+		if e == nil {
+			continue
+		}
+		for i := range wco {
+			lo, hi := e.Min[i], e.Max[i]
+			if !e.Machine {
+				lo += wco[i]
+				hi += wco[i]
+			}
+			axis := "XYZ"[i]
+			switch {
+			case lo < min[i]-travelTolerance:
+				res = append(res, TravelError{Line: n, Axis: axis, Position: lo, Limit: min[i], Home: s.homeEnd(i, -1)})
+			case hi > max[i]+travelTolerance:
+				res = append(res, TravelError{Line: n, Axis: axis, Position: hi, Limit: max[i], Home: s.homeEnd(i, 1)})
+			}
+		}
+	}
+	return res, nil
+}
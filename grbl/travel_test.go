@@ -0,0 +1,47 @@
+package grbl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckTravel(t *testing.T) {
+	var s Settings
+	s.Homing = true
+	s.MaxTravel.X, s.MaxTravel.Y, s.MaxTravel.Z = Millimeter*300, Millimeter*200, Millimeter*80
+	s.HomingDirectionInvert.Y = true
+
+	lines := testLines(t, `
+G21 G90
+G0 Z5
+G0 X10 Y10
+G2 X30 Y10 I10 J0
+G1 Z-35
+G53 G0 Z1
+`)
+	errs, err := CheckTravel(s, [3]float64{-315, -205, -50}, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []TravelError{
+		{Line: 2, Axis: 'X', Position: -305, Limit: -300},
+		{Line: 3, Axis: 'X', Position: -305, Limit: -300},
+		{Line: 4, Axis: 'Z', Position: -85, Limit: -80, Home: false},
+		{Line: 5, Axis: 'Z', Position: 1, Limit: 0, Home: true},
+	}
+	if len(errs) != len(exp) {
+		t.Fatalf("got %v; want %v", errs, exp)
+	}
+	for i, e := range errs {
+		x := exp[i]
+		if e.Line != x.Line || e.Axis != x.Axis || e.Home != x.Home || math.Abs(e.Position-x.Position) > 0.01 || e.Limit != x.Limit {
+			t.Errorf("error[%d] = %v; want %v", i, e, x)
+		}
+	}
+
+	s.Homing = false
+	errs, err = CheckTravel(s, [3]float64{}, lines)
+	if err != nil || errs != nil {
+		t.Errorf("CheckTravel(no homing) = %v, %v; want nil, nil", errs, err)
+	}
+}
@@ -47,6 +47,13 @@ They may be specified by a preceding `_` followed by an identifier, and the coor
 
 	_ZERO{1,0,2}
 
+Height Maps
+
+A height map of the work surface, used to level the job, is specified by a preceding `#` followed by the grid within curly-braces
+(X and Y of the first point, X and Y step, columns and rows) and then the Z value of each point, row by row.
+
+	#{0,0,10,10,3,2}{0,0.02,0.05,-0.01,0.01,0.03}
+
 Comments
 
 Comments may be specified the same way as in LinuxCNC and Grbl. That is anything following `;` to the line end, or anything between parentheses.
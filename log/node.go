@@ -70,6 +70,14 @@ type Coordinates struct {
 	Values []float64
 }
 
+// HeightMap is a grid of Z measurements of the work surface, used to level a job
+// (e.g. when engraving PCBs).
+type HeightMap struct {
+	Node
+
+	Map gcode.HeightMap
+}
+
 // SerialData is a log of functional data sent over the wire between the CNC controller and software.
 //
 // Generally, only GCode and confirmations are logged, and stateful data, like mode or jogging, is omitted.
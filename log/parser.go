@@ -161,14 +161,11 @@ func (p *Parser) scanGCode() (*GCode, error) {
 	}, nil
 }
 
-func (p *Parser) scanCoordinates() (*Coordinates, error) {
-	n := p.scan()
-	name := n.lit[1:]
-	start := n.pos
-	var end Pos
-	n = p.scanIgnoreWhitespace()
+// scanValues will parse a list of numbers within curly-braces, returning the end position.
+func (p *Parser) scanValues() ([]float64, Pos, error) {
+	n := p.scanIgnoreWhitespace()
 	if n.tok != TokenLBrace {
-		return nil, n.unexpectedErr("'{'")
+		return nil, n.end, n.unexpectedErr("'{'")
 	}
 	vals := make([]float64, 0, 10)
 	var v float64
@@ -176,24 +173,33 @@ func (p *Parser) scanCoordinates() (*Coordinates, error) {
 	for {
 		n = p.scanIgnoreWhitespace()
 		if n.tok != TokenNumber {
-			return nil, n.unexpectedErr("a numeric value")
+			return nil, n.end, n.unexpectedErr("a numeric value")
 		}
 
 		v, err = strconv.ParseFloat(n.lit, 64)
 		if err != nil {
-			return nil, n.syntaxErr(err)
+			return nil, n.end, n.syntaxErr(err)
 		}
 		vals = append(vals, v)
 
 		n = p.scanIgnoreWhitespace()
 		if n.tok == TokenRBrace {
-			end = n.end
-			break
+			return vals, n.end, nil
 		} else if n.tok == TokenComma {
 			continue
 		}
 
-		return nil, n.unexpectedErr("'}' or ','")
+		return nil, n.end, n.unexpectedErr("'}' or ','")
+	}
+}
+
+func (p *Parser) scanCoordinates() (*Coordinates, error) {
+	n := p.scan()
+	name := n.lit[1:]
+	start := n.pos
+	vals, end, err := p.scanValues()
+	if err != nil {
+		return nil, err
 	}
 
 	return &Coordinates{
@@ -203,6 +209,36 @@ func (p *Parser) scanCoordinates() (*Coordinates, error) {
 	}, nil
 }
 
+func (p *Parser) scanHeightMap() (*HeightMap, error) {
+	n := p.scan()
+	start := n.pos
+	grid, _, err := p.scanValues()
+	if err != nil {
+		return nil, err
+	}
+	if len(grid) != 6 {
+		return nil, n.syntaxErr(fmt.Errorf("expected 6 grid values but got %d", len(grid)))
+	}
+	z, end, err := p.scanValues()
+	if err != nil {
+		return nil, err
+	}
+	m := gcode.HeightMap{
+		X: grid[0], Y: grid[1],
+		StepX: grid[2], StepY: grid[3],
+		Cols: int(grid[4]), Rows: int(grid[5]),
+		Z: z,
+	}
+	if m.Cols*m.Rows != len(z) {
+		return nil, n.syntaxErr(fmt.Errorf("expected %d values for %dx%d grid but got %d", m.Cols*m.Rows, m.Cols, m.Rows, len(z)))
+	}
+
+	return &HeightMap{
+		Map:  m,
+		Node: node{pos: start, end: end},
+	}, nil
+}
+
 func (p *Parser) scanSerial() (*SerialData, error) {
 	n := p.scan()
 	var d Direction
@@ -249,11 +285,14 @@ func (p *Parser) Parse() (Node, error) {
 	case TokenGT, TokenLT:
 		p.unscan()
 		return p.scanSerial()
+	case TokenHash:
+		p.unscan()
+		return p.scanHeightMap()
 	case TokenEOF:
 		return nil, io.EOF
 	case TokenIllegal:
 		return nil, n.illegalErr()
 	}
 
-	return nil, n.unexpectedErr("flag, gcode, send, recv, or EOF")
+	return nil, n.unexpectedErr("flag, gcode, coordinates, height map, send, recv, or EOF")
 }
@@ -225,6 +225,8 @@ func (s *Scanner) Scan() (tok Token, lit string) {
 		return TokenGT, ">"
 	case '<':
 		return TokenLT, "<"
+	case '#':
+		return TokenHash, "#"
 	case ';':
 		s.unread()
 		return s.scanType(TokenLineComment, isLine)
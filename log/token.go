@@ -19,6 +19,7 @@ const (
 	TokenEquals
 	TokenGT
 	TokenLT
+	TokenHash
 	TokenIdentifier
 	TokenWhitespace
 	TokenNewLine
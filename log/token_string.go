@@ -4,9 +4,9 @@ package log
 
 import "fmt"
 
-const _Token_name = "TokenLineCommentTokenBlockCommentTokenWordTokenLBraceTokenRBraceTokenCommaTokenNumberTokenFlagTokenStringTokenEqualsTokenGTTokenLTTokenIdentifierTokenWhitespaceTokenNewLineTokenIllegalTokenUnterminatedStringTokenEOF"
+const _Token_name = "TokenLineCommentTokenBlockCommentTokenWordTokenLBraceTokenRBraceTokenCommaTokenNumberTokenFlagTokenStringTokenEqualsTokenGTTokenLTTokenHashTokenIdentifierTokenWhitespaceTokenNewLineTokenIllegalTokenUnterminatedStringTokenEOF"
 
-var _Token_index = [...]uint8{0, 16, 33, 42, 53, 64, 74, 85, 94, 105, 116, 123, 130, 145, 160, 172, 184, 207, 215}
+var _Token_index = [...]uint8{0, 16, 33, 42, 53, 64, 74, 85, 94, 105, 116, 123, 130, 139, 154, 169, 181, 193, 216, 224}
 
 func (i Token) String() string {
 	if i < 0 || i >= Token(len(_Token_index)-1) {
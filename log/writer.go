@@ -5,6 +5,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mastercactapus/gg/gcode"
 )
@@ -22,14 +23,23 @@ func (f FormatError) Error() string {
 	)
 }
 
+// Writer writes log records. It is safe for concurrent use.
 type Writer struct {
-	w io.Writer
+	mx sync.Mutex
+	w  io.Writer
 }
 
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{w: w}
 }
 
+func (w *Writer) write(s string) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	_, err := io.WriteString(w.w, s)
+	return err
+}
+
 func commentString(value string) string {
 	if value == "" {
 		return ""
@@ -38,8 +48,7 @@ func commentString(value string) string {
 }
 
 func (w *Writer) Comment(value string) error {
-	_, err := io.WriteString(w.w, strings.TrimSpace(commentString(value))+"\n")
-	return err
+	return w.write(strings.TrimSpace(commentString(value)) + "\n")
 }
 
 func (w *Writer) Flag(name, value, comment string) error {
@@ -55,8 +64,7 @@ func (w *Writer) Flag(name, value, comment string) error {
 		return &FormatError{Type: "Flag", Value: name + "=" + value, Reason: "name must begin with lower-case letter or digit"}
 	}
 
-	_, err := io.WriteString(w.w, "@"+name+"="+strconv.Quote(value)+commentString(comment)+"\n")
-	return err
+	return w.write("@" + name + "=" + strconv.Quote(value) + commentString(comment) + "\n")
 }
 
 func (w *Writer) GCode(l gcode.Line) error {
@@ -64,8 +72,7 @@ func (w *Writer) GCode(l gcode.Line) error {
 		return nil
 	}
 
-	_, err := io.WriteString(w.w, l.String()+"\n")
-	return err
+	return w.write(l.String() + "\n")
 }
 
 func (w *Writer) Coordinates(id string, coords []float64) error {
@@ -79,19 +86,29 @@ func (w *Writer) Coordinates(id string, coords []float64) error {
 		return &FormatError{Type: "Coordinates", Value: "coords", Reason: "must contain at least one coordinate"}
 	}
 
-	s := make([]string, len(coords))
-	for i, v := range coords {
+	return w.write("_" + id + formatValues(coords) + "\n")
+}
+
+func formatValues(vals []float64) string {
+	s := make([]string, len(vals))
+	for i, v := range vals {
 		s[i] = strconv.FormatFloat(v, 'f', -1, 64)
 	}
-	_, err := io.WriteString(w.w, "_"+id+"{"+strings.Join(s, ",")+"}\n")
-	return err
+	return "{" + strings.Join(s, ",") + "}"
+}
+
+func (w *Writer) HeightMap(m *gcode.HeightMap) error {
+	if m.Cols < 1 || m.Rows < 1 || len(m.Z) != m.Cols*m.Rows {
+		return &FormatError{Type: "HeightMap", Value: "Z", Reason: "must contain a value for each point"}
+	}
+
+	grid := []float64{m.X, m.Y, m.StepX, m.StepY, float64(m.Cols), float64(m.Rows)}
+	return w.write("#" + formatValues(grid) + formatValues(m.Z) + "\n")
 }
 
 func (w *Writer) SerialSend(data string) error {
-	_, err := io.WriteString(w.w, ">"+strconv.Quote(data)+"\n")
-	return err
+	return w.write(">" + strconv.Quote(data) + "\n")
 }
 func (w *Writer) SerialRecv(data string) error {
-	_, err := io.WriteString(w.w, "<"+strconv.Quote(data)+"\n")
-	return err
+	return w.write("<" + strconv.Quote(data) + "\n")
 }
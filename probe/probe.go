@@ -0,0 +1,342 @@
+// Package probe provides canned probing routines for setting up stock on the machine.
+//
+// All routines use G38.2 and report machine coordinates (in mm) suitable for use as a work
+// coordinate system offset (e.g. with grbl.Grbl.SetWorkOffset). Edges are compensated
+// for the diameter of the probe tip.
+package probe
+
+import (
+	"errors"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrObstructed is returned if the probe was triggered while moving into position (e.g. the
+// routine was started too far from an edge).
+var ErrObstructed = errors.New("probe triggered while moving into position")
+
+// Machine is used to run probing routines. It is implemented by *grbl.Grbl.
+type Machine interface {
+	Probe(mode grbl.ProbeMode, axes gcode.Line, feed float64) (grbl.ProbeResult, error)
+	ExecLine(l gcode.Line) error
+}
+
+// Config holds the parameters of the probing routines. Distances are in mm and feeds
+// in mm/min.
+type Config struct {
+	// Diameter is the diameter of the probe tip.
+	Diameter float64
+
+	// Distance is the maximum travel when searching for a surface.
+	Distance float64
+
+	// Retract is the distance to back off before the second, slower, touch.
+	Retract float64
+
+	// Clearance is the height above the top surface when moving over the stock.
+	Clearance float64
+
+	// Depth is how far below the top surface the sides are probed.
+	Depth float64
+
+	// Offset is how far past an edge to move before probing it. It must be greater than the
+	// distance from the starting point to the edge.
+	Offset float64
+
+	FastFeed float64
+	SlowFeed float64
+}
+
+// DefaultConfig returns a Config suitable for a typical 1/8" probe tip.
+func DefaultConfig() Config {
+	return Config{
+		Diameter:  3.175,
+		Distance:  25,
+		Retract:   2,
+		Clearance: 5,
+		Depth:     5,
+		Offset:    20,
+		FastFeed:  200,
+		SlowFeed:  25,
+	}
+}
+
+// Prober runs probing routines on a Machine.
+type Prober struct {
+	m Machine
+	c Config
+}
+
+// NewProber will create a new Prober using m with the given Config.
+func NewProber(m Machine, c Config) *Prober {
+	return &Prober{m: m, c: c}
+}
+
+func axisIndex(axis byte) int {
+	switch axis {
+	case 'X':
+		return 0
+	case 'Y':
+		return 1
+	case 'Z':
+		return 2
+	}
+	return -1
+}
+
+// moveTo will rapid to the given machine coordinates.
+func (p *Prober) moveTo(axis byte, v float64) error {
+	return p.m.ExecLine(gcode.Line{
+		{Type: 'G', Value: 53},
+		{Type: 'G', Value: 0},
+		{Type: axis, Value: v},
+	})
+}
+
+// Touch will probe along axis in dir (1 or -1) up to dist, first quickly and then slowly
+// (e.g. to find a touch plate). The machine position of the second touch is returned, and the
+// probe is left backed off by Retract.
+func (p *Prober) Touch(axis byte, dir, dist float64) ([3]float64, error) {
+	idx := axisIndex(axis)
+	r, err := p.m.Probe(grbl.ProbeToward, gcode.Line{{Type: axis, Value: dir * dist}}, p.c.FastFeed)
+	if err != nil {
+		return r.Position, err
+	}
+	back := r.Position[idx] - dir*p.c.Retract
+	err = p.moveTo(axis, back)
+	if err != nil {
+		return r.Position, err
+	}
+	r, err = p.m.Probe(grbl.ProbeToward, gcode.Line{{Type: axis, Value: dir * 2 * p.c.Retract}}, p.c.SlowFeed)
+	if err != nil {
+		return r.Position, err
+	}
+	return r.Position, p.moveTo(axis, back)
+}
+
+// descend will lower Z by dist, returning ErrObstructed if the probe is triggered on the way.
+func (p *Prober) descend(dist float64) error {
+	r, err := p.m.Probe(grbl.ProbeTowardNoError, gcode.Line{{Type: 'Z', Value: -dist}}, p.c.FastFeed)
+	if err != nil {
+		return err
+	}
+	if r.Success {
+		return ErrObstructed
+	}
+	return nil
+}
+
+// top will find the top surface below the probe, and move Clearance above it.
+// The position of the probe is returned.
+func (p *Prober) top() ([3]float64, error) {
+	pos, err := p.Touch('Z', -1, p.c.Distance)
+	if err != nil {
+		return pos, pkgerrors.Wrap(err, "probe top")
+	}
+	pos[2] += p.c.Clearance
+	return pos, p.moveTo('Z', pos[2])
+}
+
+// side will move from start (Clearance above the top surface) past an edge in dir along axis,
+// and probe back toward it below the top surface. The probe is returned to start afterwards.
+func (p *Prober) side(start [3]float64, axis byte, dir float64) (float64, error) {
+	idx := axisIndex(axis)
+	err := p.moveTo(axis, start[idx]+dir*p.c.Offset)
+	if err != nil {
+		return 0, err
+	}
+	err = p.descend(p.c.Clearance + p.c.Depth)
+	if err != nil {
+		return 0, err
+	}
+	pos, err := p.Touch(axis, -dir, p.c.Offset)
+	if err != nil {
+		return 0, err
+	}
+	err = p.moveTo('Z', start[2])
+	if err == nil {
+		err = p.moveTo(axis, start[idx])
+	}
+
+	// the probe was moving in -dir, the edge is on the far side of the tip
+	return pos[idx] - dir*p.c.Diameter/2, err
+}
+
+// Surface will find the top surface below the probe, returning its Z position.
+func (p *Prober) Surface() (gcode.Line, error) {
+	pos, err := p.top()
+	if err != nil {
+		return nil, err
+	}
+	return gcode.Line{{Type: 'Z', Value: pos[2] - p.c.Clearance}}, nil
+}
+
+// Edge will find an edge by probing along axis in dir (1 or -1) from the current position,
+// which must be beside the stock and below its top surface.
+func (p *Prober) Edge(axis byte, dir float64) (gcode.Line, error) {
+	if axisIndex(axis) == -1 {
+		return nil, errors.New("invalid axis " + string(axis))
+	}
+	pos, err := p.Touch(axis, dir, p.c.Distance)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "probe edge")
+	}
+	return gcode.Line{{Type: axis, Value: pos[axisIndex(axis)] + dir*p.c.Diameter/2}}, nil
+}
+
+// Corner will find an outside corner of the stock. The probe must start above the top
+// surface, within Offset of both edges. The direction of the X and Y edges from the probe is
+// given by xDir and yDir (e.g. -1, -1 for the front-left corner).
+func (p *Prober) Corner(xDir, yDir float64) (gcode.Line, error) {
+	start, err := p.top()
+	if err != nil {
+		return nil, err
+	}
+	x, err := p.side(start, 'X', xDir)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "probe X edge")
+	}
+	y, err := p.side(start, 'Y', yDir)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "probe Y edge")
+	}
+	return gcode.Line{
+		{Type: 'X', Value: x},
+		{Type: 'Y', Value: y},
+		{Type: 'Z', Value: start[2] - p.c.Clearance},
+	}, nil
+}
+
+// Bore will find the center and diameter of a hole, up to twice Distance across. The probe must start
+// inside it, below the surface. The probe is left at the center.
+func (p *Prober) Bore() (gcode.Line, float64, error) {
+	var center [2]float64
+	var size [2]float64
+	// X is measured again once centered in Y, as the first pass may be off-center
+	for _, axis := range []byte{'X', 'Y', 'X'} {
+		i := axisIndex(axis)
+		a, err := p.Touch(axis, 1, p.c.Distance)
+		if err != nil {
+			return nil, 0, pkgerrors.Wrap(err, "probe +"+string(axis))
+		}
+		b, err := p.Touch(axis, -1, 2*p.c.Distance)
+		if err != nil {
+			return nil, 0, pkgerrors.Wrap(err, "probe -"+string(axis))
+		}
+		center[i] = (a[i] + b[i]) / 2
+		size[i] = a[i] - b[i] + p.c.Diameter
+		err = p.moveTo(axis, center[i])
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return gcode.Line{
+		{Type: 'X', Value: center[0]},
+		{Type: 'Y', Value: center[1]},
+	}, (size[0] + size[1]) / 2, nil
+}
+
+// Boss will find the center and diameter of a round (or rectangular) boss. The probe must start
+// above it, within Offset of its sides. The probe is left above the center.
+func (p *Prober) Boss() (gcode.Line, float64, error) {
+	start, err := p.top()
+	if err != nil {
+		return nil, 0, err
+	}
+	var size [2]float64
+	for i, axis := range []byte{'X', 'Y'} {
+		a, err := p.side(start, axis, 1)
+		if err != nil {
+			return nil, 0, pkgerrors.Wrap(err, "probe +"+string(axis))
+		}
+		b, err := p.side(start, axis, -1)
+		if err != nil {
+			return nil, 0, pkgerrors.Wrap(err, "probe -"+string(axis))
+		}
+		start[i] = (a + b) / 2
+		size[i] = a - b
+		err = p.moveTo(axis, start[i])
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return gcode.Line{
+		{Type: 'X', Value: start[0]},
+		{Type: 'Y', Value: start[1]},
+	}, (size[0] + size[1]) / 2, nil
+}
+
+// Height will measure the height of the stock by probing its top surface, and then the table
+// past the edge in dir along axis. The probe must start above the stock, within Offset of the edge.
+func (p *Prober) Height(axis byte, dir float64) (gcode.Line, float64, error) {
+	idx := axisIndex(axis)
+	if idx == -1 || idx == 2 {
+		return nil, 0, errors.New("invalid axis " + string(axis))
+	}
+	start, err := p.top()
+	if err != nil {
+		return nil, 0, err
+	}
+	err = p.moveTo(axis, start[idx]+dir*p.c.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	table, err := p.Touch('Z', -1, p.c.Clearance+p.c.Distance)
+	if err != nil {
+		return nil, 0, pkgerrors.Wrap(err, "probe table")
+	}
+	err = p.moveTo('Z', start[2])
+	if err == nil {
+		err = p.moveTo(axis, start[idx])
+	}
+
+	top := start[2] - p.c.Clearance
+	return gcode.Line{{Type: 'Z', Value: top}}, top - table[2], err
+}
+
+// HeightMap will measure the Z position of each point of h, in work coordinates. The work coordinate
+// offset (machine position of work zero) is given by wco. The probe must start above the surface, within
+// Distance of it, and is left Clearance above the highest point.
+func (p *Prober) HeightMap(h *gcode.HeightMap, wco [3]float64) error {
+	var safe float64
+	for r := 0; r < h.Rows; r++ {
+		for i := 0; i < h.Cols; i++ {
+			// serpentine, to avoid long moves between rows
+			c := i
+			if r%2 == 1 {
+				c = h.Cols - 1 - i
+			}
+			dist := p.c.Distance
+			if r > 0 || i > 0 {
+				err := p.moveTo('Z', safe)
+				if err != nil {
+					return err
+				}
+				dist += p.c.Clearance
+			}
+			x, y := h.Point(c, r)
+			err := p.m.ExecLine(gcode.Line{
+				{Type: 'G', Value: 53},
+				{Type: 'G', Value: 0},
+				{Type: 'X', Value: x + wco[0]},
+				{Type: 'Y', Value: y + wco[1]},
+			})
+			if err != nil {
+				return err
+			}
+			pos, err := p.Touch('Z', -1, dist)
+			if err != nil {
+				return pkgerrors.Wrapf(err, "probe point %d,%d", c, r)
+			}
+			h.Z[r*h.Cols+c] = pos[2] - wco[2]
+			if r == 0 && i == 0 || pos[2]+p.c.Clearance > safe {
+				safe = pos[2] + p.c.Clearance
+			}
+		}
+	}
+	return p.moveTo('Z', safe)
+}
@@ -0,0 +1,177 @@
+package probe
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl"
+)
+
+var _ Machine = (*grbl.Grbl)(nil)
+
+// testMachine moves a probe tip of the given radius through a solid in machine coordinates.
+type testMachine struct {
+	t      *testing.T
+	pos    [3]float64
+	radius float64
+	solid  func(p [3]float64, r float64) bool
+}
+
+func (m *testMachine) Probe(mode grbl.ProbeMode, axes gcode.Line, feed float64) (grbl.ProbeResult, error) {
+	var d [3]float64
+	for _, w := range axes {
+		d[axisIndex(w.Type)] = w.Value
+	}
+	if m.solid(m.pos, m.radius) {
+		return grbl.ProbeResult{}, grbl.AlarmProbeInitial
+	}
+	n := int(math.Sqrt(d[0]*d[0]+d[1]*d[1]+d[2]*d[2]) / 0.001)
+	for i := 1; i <= n; i++ {
+		var p [3]float64
+		for j := range p {
+			p[j] = m.pos[j] + d[j]*float64(i)/float64(n)
+		}
+		if m.solid(p, m.radius) {
+			m.pos = p
+			return grbl.ProbeResult{Position: p, Success: true}, nil
+		}
+	}
+	for j := range m.pos {
+		m.pos[j] += d[j]
+	}
+	r := grbl.ProbeResult{Position: m.pos}
+	if mode == grbl.ProbeToward {
+		return r, grbl.AlarmProbeContact
+	}
+	return r, nil
+}
+
+func (m *testMachine) ExecLine(l gcode.Line) error {
+	if l.Value('G') != 53 {
+		m.t.Fatalf("unexpected line %s", l.String())
+	}
+	for _, w := range l {
+		if i := axisIndex(w.Type); i != -1 {
+			m.pos[i] = w.Value
+		}
+	}
+	return nil
+}
+
+// block is 100x50x20 with the front-left-top corner at 10,20,-30
+func block(p [3]float64, r float64) bool {
+	return p[0] > 10-r && p[0] < 110+r && p[1] > 20-r && p[1] < 70+r && p[2] < -30
+}
+
+func checkLine(t *testing.T, name string, l gcode.Line, exp gcode.Line) {
+	t.Helper()
+	if len(l) != len(exp) {
+		t.Fatalf("%s = %s; want %s", name, l.String(), exp.String())
+	}
+	for i := range l {
+		if l[i].Type != exp[i].Type || math.Abs(l[i].Value-exp[i].Value) > 0.01 {
+			t.Errorf("%s = %s; want %s", name, l.String(), exp.String())
+			return
+		}
+	}
+}
+
+func TestProber_Corner(t *testing.T) {
+	c := DefaultConfig()
+	m := &testMachine{t: t, pos: [3]float64{15, 25, -10}, radius: c.Diameter / 2, solid: block}
+	p := NewProber(m, c)
+
+	l, err := p.Corner(-1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLine(t, "Corner", l, gcode.Line{{Type: 'X', Value: 10}, {Type: 'Y', Value: 20}, {Type: 'Z', Value: -30}})
+	checkLine(t, "pos", gcode.Line{{Type: 'X', Value: m.pos[0]}, {Type: 'Y', Value: m.pos[1]}, {Type: 'Z', Value: m.pos[2]}},
+		gcode.Line{{Type: 'X', Value: 15}, {Type: 'Y', Value: 25}, {Type: 'Z', Value: -25}})
+
+	// starting too far from the edge will hit the stock
+	m.pos = [3]float64{50, 40, -10}
+	_, err = p.Corner(-1, -1)
+	if err == nil {
+		t.Error("Corner err = nil; want error")
+	}
+}
+
+func TestProber_Height(t *testing.T) {
+	c := DefaultConfig()
+	table := func(p [3]float64, r float64) bool { return block(p, r) || p[2] < -50 }
+	m := &testMachine{t: t, pos: [3]float64{100, 60, -10}, radius: c.Diameter / 2, solid: table}
+	p := NewProber(m, c)
+
+	l, h, err := p.Height('X', 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLine(t, "Height", l, gcode.Line{{Type: 'Z', Value: -30}})
+	if math.Abs(h-20) > 0.01 {
+		t.Errorf("height = %f; want 20", h)
+	}
+}
+
+func TestProber_Bore(t *testing.T) {
+	c := DefaultConfig()
+	hole := func(p [3]float64, r float64) bool {
+		dx, dy := p[0]-30, p[1]-40
+		return math.Sqrt(dx*dx+dy*dy) > 10-r
+	}
+	m := &testMachine{t: t, pos: [3]float64{27, 45, -40}, radius: c.Diameter / 2, solid: hole}
+	p := NewProber(m, c)
+
+	l, d, err := p.Bore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLine(t, "Bore", l, gcode.Line{{Type: 'X', Value: 30}, {Type: 'Y', Value: 40}})
+	if math.Abs(d-20) > 0.1 {
+		t.Errorf("diameter = %f; want 20", d)
+	}
+}
+
+func TestProber_Boss(t *testing.T) {
+	c := DefaultConfig()
+	m := &testMachine{t: t, pos: [3]float64{58, 42, -10}, radius: c.Diameter / 2, solid: func(p [3]float64, r float64) bool {
+		return block(p, r) && p[0] > 45-r && p[0] < 75+r && p[1] > 30-r && p[1] < 60+r
+	}}
+	p := NewProber(m, c)
+
+	l, d, err := p.Boss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLine(t, "Boss", l, gcode.Line{{Type: 'X', Value: 60}, {Type: 'Y', Value: 45}})
+	if math.Abs(d-30) > 0.01 {
+		t.Errorf("size = %f; want 30", d)
+	}
+}
+
+func TestProber_HeightMap(t *testing.T) {
+	c := DefaultConfig()
+	// a warped board, with its top 1mm higher at the back-right corner
+	surface := func(x, y float64) float64 { return -30 + x*y/5000 }
+	m := &testMachine{t: t, pos: [3]float64{15, 25, -10}, radius: c.Diameter / 2, solid: func(p [3]float64, r float64) bool {
+		return p[2] < surface(p[0], p[1])
+	}}
+	p := NewProber(m, c)
+
+	wco := [3]float64{10, 20, -30}
+	h := gcode.NewHeightMap([2]float64{0, 0}, [2]float64{100, 50}, 25)
+	err := p.HeightMap(h, wco)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r := 0; r < h.Rows; r++ {
+		for c := 0; c < h.Cols; c++ {
+			x, y := h.Point(c, r)
+			exp := surface(x+wco[0], y+wco[1]) - wco[2]
+			if z := h.Z[r*h.Cols+c]; math.Abs(z-exp) > 0.01 {
+				t.Errorf("Z[%d,%d] = %f; want %f", c, r, z, exp)
+			}
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"regexp"
 	"runtime/pprof"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,7 +18,9 @@ import (
 
 	"github.com/mastercactapus/gg/gcode"
 	"github.com/mastercactapus/gg/grbl"
+	"github.com/mastercactapus/gg/grbl/sim"
 	"github.com/mastercactapus/gg/log"
+	"github.com/mastercactapus/gg/svg"
 	"github.com/mastercactapus/gg/ui"
 	termbox "github.com/nsf/termbox-go"
 )
@@ -28,7 +32,29 @@ var (
 	rate    = flag.Int("b", 115200, "Baudrate of the serial port.")
 	resume  = flag.Bool("resume", false, "Resume an existing log (implies -run).")
 	remote  = flag.String("remote", "", "Connect to a remote serial port.")
+	gcodeIn = flag.String("gcode", "", "Load GCode from a file (e.g. CAM output) instead of generating it.")
+	simMode = flag.Bool("sim", false, "Use a simulated machine instead of a serial port.")
+	keys    = flag.String("keys", "", "Key binding file for the UI, with a key name and action on each line (e.g. \"Ctrl-R run\").")
+	svgOut  = flag.String("svg", "", "Write an SVG image of the toolpath to a file (e.g. for review) before printing or running GCode.")
+	plate   = ParamUnitD("touch-plate", "Thickness of the touch plate used to probe Z zero.", 0)
+	tip     = ParamUnitD("probe-diameter", "Tip diameter of the probe used to find edges.", 3.175)
+	toolZ   = ParamUnitD("tool-change-clearance", "Distance below machine Z zero to retract to for tool changes.", 1)
 	l       *log.Writer
+
+	// resumeZero is the last work zero (in machine coordinates) recorded in a resumed log.
+	resumeZero []float64
+
+	// resumeLine is the last line number acknowledged by Grbl in a resumed log.
+	resumeLine int
+
+	// jobName is the name of the program, from Setup.
+	jobName string
+
+	// stock is the stock set by the program, if any.
+	stock *svg.Box
+
+	// resumeMap is the last height map recorded in a resumed log.
+	resumeMap *gcode.HeightMap
 )
 
 func failf(s string, args ...interface{}) {
@@ -65,20 +91,6 @@ func (l *logger) Read(p []byte) (int, error) {
 
 func Run(f func()) {
 	if *resume {
-		var err error
-		var p io.ReadWriteCloser
-		if *remote != "" {
-			p, err = net.Dial("tcp", *remote)
-			if err != nil {
-				failf("failed to open remote serial port: %v", err)
-			}
-		} else {
-			p, err = serial.Open(*port, &serial.Mode{BaudRate: *rate})
-			if err != nil {
-				failf("failed to open serial port: %v", err)
-			}
-		}
-
 		fdrw, _ := os.Create("RWDATA.log")
 		fdr, _ := os.Create("RDATA.log")
 		fdw, _ := os.Create("WDATA.log")
@@ -86,11 +98,62 @@ func Run(f func()) {
 		defer fdr.Close()
 		defer fdw.Close()
 
-		c := grbl.NewGrbl(&logger{ReadWriteCloser: p, rw: fdrw, r: fdr, w: fdw})
-		u, err := ui.NewJobUI(c, lines)
+		var c *grbl.Grbl
+		if *simMode {
+			m := sim.NewMachine()
+			// stock covering the whole table, so probing can be tried out
+			m.SetStock([3]float64{-200, -200, -200}, [3]float64{0, 0, -100})
+			c = grbl.NewGrbl(&logger{ReadWriteCloser: m, rw: fdrw, r: fdr, w: fdw})
+		} else {
+			t, err := grbl.DialTransport(func() (io.ReadWriteCloser, error) {
+				var p io.ReadWriteCloser
+				var err error
+				if *remote != "" {
+					p, err = net.Dial("tcp", *remote)
+				} else {
+					p, err = serial.Open(*port, &serial.Mode{BaudRate: *rate})
+				}
+				if err != nil {
+					return nil, err
+				}
+				return &logger{ReadWriteCloser: p, rw: fdrw, r: fdr, w: fdw}, nil
+			})
+			if err != nil {
+				failf("failed to open serial port: %v", err)
+			}
+			c = grbl.NewGrbl(t)
+		}
+		info, err := c.Connect(5 * time.Second)
+		if err != nil {
+			failf("failed to connect: %v", err)
+		}
+		err = l.Comment(fmt.Sprintf("Connected: Grbl %s, build %s, options %s", info.Version, info.Build, info.Options))
+		if err != nil {
+			failf("failed to write to log: %v", err)
+		}
+		u, err := ui.NewJobUI(c, lines, l)
 		if err != nil {
 			failf("failed to launch UI: %v", err)
 		}
+		if resumeZero != nil {
+			u.OfferRestoreZero(resumeZero)
+		}
+		u.SetTouchPlate(*plate)
+		u.SetProbeDiameter(*tip)
+		u.SetToolChangeClearance(*toolZ)
+		if *keys != "" {
+			km, err := ui.LoadKeymap(*keys)
+			if err != nil {
+				failf("failed to load key bindings: %v", err)
+			}
+			u.SetKeymap(km)
+		}
+		if resumeMap != nil {
+			u.SetHeightMap(resumeMap)
+		}
+		if resumeLine > 0 {
+			u.OfferResume(resumeLine)
+		}
 
 		err = u.Start()
 		if err != nil {
@@ -98,16 +161,30 @@ func Run(f func()) {
 		}
 		return
 	}
-	err := l.Comment("Run(): Generate GCode")
-	if err != nil {
-		failf("failed to write to log: %v", err)
+	if *gcodeIn != "" {
+		err := loadGCode(*gcodeIn)
+		if err != nil {
+			failf("failed to load gcode: %v", err)
+		}
+	} else {
+		err := l.Comment("Run(): Generate GCode")
+		if err != nil {
+			failf("failed to write to log: %v", err)
+		}
+		print(gcode.Line{{Type: 'G', Value: 21}})
+		f()
+	}
+
+	if *svgOut != "" {
+		err := writeSVG(*svgOut)
+		if err != nil {
+			failf("failed to write SVG: %v", err)
+		}
 	}
-	print(gcode.Line{{Type: 'G', Value: 21}})
-	f()
 
 	if *run {
 		for _, line := range lines {
-			err = l.GCode(line)
+			err := l.GCode(line)
 			if err != nil {
 				failf("failed to write gcode to log: %v", err)
 			}
@@ -119,9 +196,43 @@ func Run(f func()) {
 	}
 }
 
+func writeSVG(name string) error {
+	fd, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = svg.Write(fd, lines, svg.Options{Title: jobName, Stock: stock})
+	if err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func loadGCode(name string) error {
+	fd, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	blocks, err := gcode.Parse(fd)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, gcode.Lines(blocks)...)
+
+	return l.Comment("Run(): Load GCode from " + name)
+}
+
+var lineNumRx = regexp.MustCompile("^N([0-9]+)")
+
 func resumeState(r io.Reader) error {
 	p := log.NewParser(r)
 
+	// line number of the last line sent, responses are logged immediately after
+	sent := -1
+
 	node, err := p.Parse()
 	for err == nil {
 		switch n := node.(type) {
@@ -129,6 +240,27 @@ func resumeState(r io.Reader) error {
 			err = flag.Set(n.Name, n.Value)
 		case *log.GCode:
 			lines = append(lines, n.Line)
+		case *log.Coordinates:
+			if n.ID == "ZERO" && len(n.Values) == 3 {
+				// last one wins
+				resumeZero = n.Values
+			}
+		case *log.HeightMap:
+			m := n.Map
+			resumeMap = &m
+		case *log.SerialData:
+			if n.Direction == log.DirectionSend {
+				sent = 0
+				if m := lineNumRx.FindStringSubmatch(n.Data); m != nil {
+					sent, _ = strconv.Atoi(m[1])
+				}
+				break
+			}
+			// errors are acknowledged too, Grbl has moved on from the line
+			if sent > resumeLine {
+				resumeLine = sent
+			}
+			sent = -1
 		}
 		if err != nil {
 			return err
@@ -210,6 +342,7 @@ func Setup(c Config) {
 		os.Exit(1)
 	}
 
+	jobName = c.Name
 	err := l.Comment("Setup(): " + c.Name)
 	if err != nil {
 		failf("failed to log to file: %v", err)
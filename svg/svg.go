@@ -0,0 +1,253 @@
+// Package svg renders the toolpath of a program as an SVG image, for reviewing jobs before they are cut.
+//
+// The image is a top (XY) view in mm. Rapid moves are drawn as dashed lines, feed moves are colored by
+// depth, and arcs are drawn as true arcs with a distinct line style.
+package svg
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+// arcTolerance is used to draw arcs outside of the XY plane, in mm.
+const arcTolerance = 0.01
+
+// Depth colors, from the highest to lowest Z of feed moves.
+var (
+	shallowColor = [3]float64{0x9e, 0xca, 0xe1}
+	deepColor    = [3]float64{0x08, 0x30, 0x6b}
+)
+
+// Box is a rectangular volume, in mm.
+type Box struct {
+	Min, Max [3]float64
+}
+
+// Options control how a program is drawn.
+type Options struct {
+	Title string
+
+	// Stock, if set, is drawn below the toolpath.
+	Stock *Box
+}
+
+type segment struct {
+	rapid bool
+	from  [3]float64
+	to    [3]float64
+	arc   *gcode.Arc
+}
+
+// depth returns the lowest Z of the segment.
+func (s segment) depth() float64 {
+	z := math.Min(s.from[2], s.to[2])
+	if s.arc != nil {
+		for _, p := range s.arc.Points(arcTolerance) {
+			z = math.Min(z, p[2])
+		}
+	}
+	return z
+}
+
+// segments returns the moves of a program where the start and end are known.
+func segments(lines []gcode.Line) ([]segment, error) {
+	ext, err := gcode.Extents(lines)
+	if err != nil {
+		return nil, err
+	}
+	var res []segment
+	in := gcode.NewInterpreter()
+	known := false
+	for i, l := range lines {
+		from := in.State().Position
+		in.Exec(l)
+		e := ext[i]
+		if e == nil {
+			continue
+		}
+		if e.Machine || math.IsNaN(e.Min[0]) || math.IsNaN(e.Min[1]) || math.IsNaN(e.Min[2]) {
+			known = false
+			continue
+		}
+		if !known {
+			known = true
+			continue
+		}
+		s := segment{
+			rapid: in.State().Motion == gcode.MotionRapid,
+			from:  from,
+			to:    in.State().Position,
+			arc:   in.Arc(),
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// writer keeps the first error, so output can be written without checking each call.
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+func f(v float64) string {
+	return fmt.Sprintf("%.3f", v)
+}
+
+func color(t float64) string {
+	var c [3]int
+	for i := range c {
+		c[i] = int(math.Round(shallowColor[i] + (deepColor[i]-shallowColor[i])*t))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
+
+// Write will render the toolpath of lines as an SVG image to w.
+func Write(w io.Writer, lines []gcode.Line, o Options) error {
+	segs, err := segments(lines)
+	if err != nil {
+		return err
+	}
+
+	var b Box
+	first := true
+	add := func(p [3]float64) {
+		for i := range p {
+			if first {
+				b.Min[i], b.Max[i] = p[i], p[i]
+				continue
+			}
+			b.Min[i] = math.Min(b.Min[i], p[i])
+			b.Max[i] = math.Max(b.Max[i], p[i])
+		}
+		first = false
+	}
+	for _, s := range segs {
+		add(s.from)
+		add(s.to)
+		if s.arc != nil {
+			for _, p := range s.arc.Points(arcTolerance) {
+				add(p)
+			}
+		}
+	}
+	if o.Stock != nil {
+		add(o.Stock.Min)
+		add(o.Stock.Max)
+	}
+
+	// depth range of feed moves, by the lowest point of each
+	top, bottom := math.Inf(-1), math.Inf(1)
+	for _, s := range segs {
+		if s.rapid {
+			continue
+		}
+		top = math.Max(top, s.depth())
+		bottom = math.Min(bottom, s.depth())
+	}
+	depth := func(s segment) float64 {
+		if top <= bottom {
+			return 1
+		}
+		return (top - s.depth()) / (top - bottom)
+	}
+
+	size := math.Max(b.Max[0]-b.Min[0], b.Max[1]-b.Min[1])
+	if size <= 0 {
+		size = 1
+	}
+	margin := size / 20
+	stroke := size / 500
+	font := size / 40
+	legend := font * 3
+
+	// Y is flipped, so the view box is in SVG coordinates
+	vx, vy := b.Min[0]-margin, -b.Max[1]-margin
+	vw, vh := b.Max[0]-b.Min[0]+2*margin, b.Max[1]-b.Min[1]+2*margin+legend
+
+	out := &writer{w: bufio.NewWriter(w)}
+	out.printf(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.printf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s" width="%smm" height="%smm">`+"\n", f(vx), f(vy), f(vw), f(vh), f(vw), f(vh))
+	if o.Title != "" {
+		out.printf("<title>%s</title>\n", html.EscapeString(o.Title))
+	}
+	out.printf("<style>\n")
+	out.printf("path{fill:none;stroke-width:%s;stroke-linecap:round;stroke-linejoin:round}\n", f(stroke))
+	out.printf(".rapid{stroke:#d62728;stroke-dasharray:%s %s;stroke-width:%s}\n", f(stroke*4), f(stroke*4), f(stroke/2))
+	out.printf(".arc{stroke-dasharray:%s %s}\n", f(stroke*12), f(stroke*2))
+	out.printf(".stock{fill:#f3e5c8;stroke:#b5986a;stroke-width:%s}\n", f(stroke))
+	out.printf("text{font-family:sans-serif;font-size:%spx}\n", f(font))
+	out.printf("</style>\n")
+
+	out.printf(`<g transform="scale(1,-1)">` + "\n")
+	if s := o.Stock; s != nil {
+		out.printf(`<rect class="stock" x="%s" y="%s" width="%s" height="%s"/>`+"\n", f(s.Min[0]), f(s.Min[1]), f(s.Max[0]-s.Min[0]), f(s.Max[1]-s.Min[1]))
+	}
+	for _, s := range segs {
+		if s.rapid {
+			out.printf(`<path class="rapid" d="M%s %sL%s %s"/>`+"\n", f(s.from[0]), f(s.from[1]), f(s.to[0]), f(s.to[1]))
+			continue
+		}
+		c := color(depth(s))
+		switch {
+		case s.arc == nil:
+			out.printf(`<path stroke="%s" d="M%s %sL%s %s"/>`+"\n", c, f(s.from[0]), f(s.from[1]), f(s.to[0]), f(s.to[1]))
+		case s.arc.Plane == gcode.PlaneXY:
+			// SVG arcs can't make a full circle, so it is drawn in two halves
+			a := s.arc
+			mid := [2]float64{
+				a.Center[0] + (a.Start[0]-a.Center[0])*math.Cos(a.Angle/2) - (a.Start[1]-a.Center[1])*math.Sin(a.Angle/2),
+				a.Center[1] + (a.Start[0]-a.Center[0])*math.Sin(a.Angle/2) + (a.Start[1]-a.Center[1])*math.Cos(a.Angle/2),
+			}
+			// the sweep flag is in the direction of increasing angle, counter-clockwise with Y up
+			sweep := 1
+			if a.Clockwise {
+				sweep = 0
+			}
+			r := f(a.Radius)
+			out.printf(`<path class="arc" stroke="%s" d="M%s %sA%s %s 0 0 %d %s %sA%s %s 0 0 %d %s %s"/>`+"\n", c,
+				f(a.Start[0]), f(a.Start[1]),
+				r, r, sweep, f(mid[0]), f(mid[1]),
+				r, r, sweep, f(a.End[0]), f(a.End[1]),
+			)
+		default:
+			out.printf(`<path class="arc" stroke="%s" d="M%s %s`, c, f(s.from[0]), f(s.from[1]))
+			for _, p := range s.arc.Points(arcTolerance) {
+				out.printf("L%s %s", f(p[0]), f(p[1]))
+			}
+			out.printf(`"/>` + "\n")
+		}
+	}
+	out.printf("</g>\n")
+
+	// legend
+	x, y := b.Min[0], -b.Min[1]+margin+font
+	out.printf(`<text x="%s" y="%s">`, f(x), f(y))
+	if o.Title != "" {
+		out.printf("%s — ", html.EscapeString(o.Title))
+	}
+	out.printf(`<tspan fill="#d62728">rapid</tspan>, feed, arc</text>` + "\n")
+	if !math.IsInf(top, 0) {
+		y += font * 1.5
+		out.printf(`<text x="%s" y="%s">depth: <tspan fill="%s">Z%s</tspan> to <tspan fill="%s">Z%s</tspan></text>`+"\n",
+			f(x), f(y), color(0), f(top), color(1), f(bottom))
+	}
+	out.printf("</svg>\n")
+
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
@@ -0,0 +1,50 @@
+package svg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mastercactapus/gg/gcode"
+)
+
+func TestWrite(t *testing.T) {
+	blocks, err := gcode.Parse(strings.NewReader(`
+G21 G90 G17
+G0 X0 Y0 Z5
+G0 X10 Y0
+G1 Z-1 F100
+G1 X20
+G2 X30 Y0 I5 J0
+G1 Z-3
+G1 X40
+G0 Z5
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = Write(&buf, gcode.Lines(blocks), Options{
+		Title: "Test <1>",
+		Stock: &Box{Max: [3]float64{50, 20, 0}, Min: [3]float64{0, 0, -5}},
+	})
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	s := buf.String()
+
+	for _, exp := range []string{
+		`<title>Test &lt;1&gt;</title>`,
+		`<rect class="stock" x="0.000" y="0.000" width="50.000" height="20.000"/>`,
+		`<path class="rapid" d="M0.000 0.000L10.000 0.000"/>`,
+		// the first feed (plunge) is the shallowest
+		`<path stroke="#9ecae1" d="M10.000 0.000L10.000 0.000"/>`,
+		`<path class="arc" stroke="#9ecae1" d="M20.000 0.000A5.000 5.000 0 0 0 25.000 5.000A5.000 5.000 0 0 0 30.000 0.000"/>`,
+		`<path stroke="#08306b" d="M30.000 0.000L40.000 0.000"/>`,
+	} {
+		if !strings.Contains(s, exp) {
+			t.Errorf("missing %s\n%s", exp, s)
+		}
+	}
+}
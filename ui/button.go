@@ -12,8 +12,13 @@ type Button struct {
 	Text        string
 	Enabled     bool
 	OnClickFunc func(x, y int)
+
+	focused bool
 }
 
+func (b *Button) CanFocus() bool      { return b.Enabled }
+func (b *Button) SetFocus(focus bool) { b.focused = focus }
+
 func (b *Button) OnClick(x, y int) {
 	if !b.Enabled {
 		return
@@ -45,5 +50,9 @@ func (b *Button) Draw(r Renderer) {
 		fg = termbox.ColorWhite
 	}
 
+	if b.focused {
+		fg |= termbox.AttrReverse
+	}
+
 	putRunesA(r, x, y, []rune(text), fg, bg)
 }
@@ -18,8 +18,13 @@ type Checkbox struct {
 	Checked     bool
 	Radio       bool
 	OnClickFunc func(x, y int, newState bool)
+
+	focused bool
 }
 
+func (c *Checkbox) CanFocus() bool      { return c.Enabled }
+func (c *Checkbox) SetFocus(focus bool) { c.focused = focus }
+
 func (c *Checkbox) OnClick(x, y int) {
 	if !c.Enabled {
 		return
@@ -62,6 +67,10 @@ func (c *Checkbox) Draw(r Renderer) {
 		bg = termbox.ColorBlue
 	}
 
+	if c.focused {
+		fg |= termbox.AttrReverse
+	}
+
 	rs := []rune(icon + " " + c.Text)
 	putRunesA(r, x, y, rs, fg, bg)
 }
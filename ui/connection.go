@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/mastercactapus/gg/grbl"
+)
+
+// handleConnection will record a change in the connection to Grbl. A job running when the connection
+// is lost is stopped, and must be confirmed by the operator before it can be resumed.
+func (j *JobUI) handleConnection(st grbl.ConnectionState) {
+	prev := j.conn
+	j.conn = st
+	switch st {
+	case grbl.ConnectionLost:
+		j.jog = nil
+		if j.jobRunning {
+			j.interrupted = true
+		}
+		if j.toolChange != nil {
+			j.toolChange = nil
+			j.toolChangeResp <- toolChangeStop
+		}
+		if j.interrupted {
+			j.logComment(fmt.Sprintf("Connection lost: job interrupted after line %d", j.ackedLine))
+		} else {
+			j.logComment("Connection lost")
+		}
+	case grbl.ConnectionSuccess:
+		if prev != grbl.ConnectionSuccess {
+			j.logComment("Reconnected")
+		}
+	}
+}
+
+// confirmReconnect handles the operator's choice after a job was interrupted by a lost connection.
+func (j *JobUI) confirmReconnect(resume bool) {
+	if !j.interrupted || j.conn != grbl.ConnectionSuccess {
+		return
+	}
+	j.interrupted = false
+	if !resume {
+		j.logComment("Interrupted job discarded")
+		return
+	}
+	j.logComment(fmt.Sprintf("Interrupted job will resume after line %d", j.ackedLine))
+	j.OfferResume(j.ackedLine)
+}
+
+func (j *JobUI) connectionStatus() Control {
+	if j.conn != grbl.ConnectionSuccess {
+		msg := "Reconnecting..."
+		if j.conn == grbl.ConnectionFailed {
+			msg = "Reconnect failed, retrying..."
+		}
+		return &Text{X: 1, Y: 3, Lines: []string{j.conn.String(), msg}}
+	}
+	return &Group{
+		X: 1, Y: 3, Height: 3,
+		Width: -1,
+		Title: fmt.Sprintf("Reconnected: job interrupted after line %d", j.ackedLine),
+		Clear: true,
+		Controls: []Control{
+			&Button{X: 1, Text: "Resume Job", Enabled: j.s.State != grbl.StateUnknown,
+				OnClickFunc: func(int, int) { j.reconnectCh <- true },
+			},
+			&Button{X: 16, Text: "Discard", Enabled: true,
+				OnClickFunc: func(int, int) { j.reconnectCh <- false },
+			},
+		},
+	}
+}
@@ -30,7 +30,19 @@ type Clickable interface {
 	OnClick(x, y int)
 }
 type Scrollable interface {
-	OnScroll(n int)
+	OnScroll(x, y int, e EventType)
+}
+
+// Focusable controls can be selected with the keyboard, and pressed like a click.
+type Focusable interface {
+	Clickable
+	CanFocus() bool
+	SetFocus(bool)
+}
+
+// Typeable controls handle key events, returning true if the key was used.
+type Typeable interface {
+	OnKey(ev termbox.Event) bool
 }
 
 type BoundedCellSetter struct {
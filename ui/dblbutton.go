@@ -12,8 +12,13 @@ type DblButton struct {
 	Text        string
 	Enabled     bool
 	OnClickFunc func(x, y int)
+
+	focused bool
 }
 
+func (b *DblButton) CanFocus() bool      { return b.Enabled }
+func (b *DblButton) SetFocus(focus bool) { b.focused = focus }
+
 func (b *DblButton) OnClick(x, y int) {
 	if !b.Enabled {
 		return
@@ -46,6 +51,10 @@ func (b *DblButton) Draw(r Renderer) {
 		fg = termbox.ColorWhite
 	}
 
+	if b.focused {
+		fg |= termbox.AttrReverse
+	}
+
 	putRunesA(r, x, y, []rune(text1), fg, bg)
 	putRunesA(r, x, y+1, []rune(text2), fg, bg)
 	putRunesA(r, x, y+2, []rune(text3), fg, bg)
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl"
 	termbox "github.com/nsf/termbox-go"
 )
 
@@ -18,7 +19,7 @@ const (
 	gcodeStateDone
 )
 
-func renderGcode(s CellSetter, x, y, w int, g gcode.Line, state gcodeState) {
+func renderGcode(s CellSetter, x, y, w int, g gcode.Line, state gcodeState, err error, cursor bool) {
 	stat := ' '
 	var fg, bg termbox.Attribute
 	switch state {
@@ -34,26 +35,236 @@ func renderGcode(s CellSetter, x, y, w int, g gcode.Line, state gcodeState) {
 		stat = 'D'
 	}
 
+	if err != nil {
+		fg = termbox.ColorRed
+		stat = 'E'
+	}
+
+	if cursor {
+		fg |= termbox.AttrReverse
+	}
+
 	line := fmt.Sprintf("[%c] %-5s %s", stat, g[0].String(), g[1:].String())
+	if err != nil {
+		line += " -- " + grbl.ErrorDescription(err)
+	}
+	if len(line) > w {
+		line = line[:w]
+	}
 	line += strings.Repeat(" ", w-len(line))
 
 	putRunesA(s, x, y, []rune(line), fg, bg)
 }
 
+type viewerPrompt int
+
+const (
+	viewerPromptNone viewerPrompt = iota
+	viewerPromptFind
+	viewerPromptGoTo
+)
+
+// scrollLines is the number of lines moved for each scroll event.
+const scrollLines = 3
+
 type GCodeViewer struct {
 	Lines        []gcode.Line
 	Active, Sent int
 
+	// Errors holds the error for each failed line, by line number.
+	Errors map[int]error
+
 	X, Y   int
 	Width  int
 	Height int
-	Top    int
+
+	// Top is the index of the first line shown. It is updated to keep Active in view
+	// when following.
+	Top int
+
+	// Follow will keep the Active line in view. Scrolling turns it off.
+	Follow bool
+
+	// Cursor is the line number selected by search or go-to-line, or 0 for none.
+	Cursor int
+
+	// Words will search for G-code words (e.g. "M6" won't match "M68") instead of text.
+	Words bool
+
+	prompt viewerPrompt
+	input  []rune
+	query  string
+	msg    string
+
+	// h is the number of lines shown by the last Draw
+	h int
+}
+
+// OnScroll will scroll the viewer, and stop following the Active line.
+func (g *GCodeViewer) OnScroll(x, y int, e EventType) {
+	g.Follow = false
+	switch e {
+	case EventTypeScrollUp:
+		g.Top -= scrollLines
+	case EventTypeScrollDown:
+		g.Top += scrollLines
+	}
+}
+
+// OnKey handles input for the search and go-to-line prompts.
+func (g *GCodeViewer) OnKey(ev termbox.Event) bool {
+	if g.prompt == viewerPromptNone {
+		return false
+	}
+	switch {
+	case ev.Key == termbox.KeyEsc:
+		g.prompt = viewerPromptNone
+	case ev.Key == termbox.KeyEnter:
+		p, input := g.prompt, string(g.input)
+		g.prompt = viewerPromptNone
+		if p == viewerPromptGoTo {
+			g.goTo(input)
+		} else {
+			g.Find(input)
+		}
+	case ev.Key == termbox.KeyTab && g.prompt == viewerPromptFind:
+		g.Words = !g.Words
+	case ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2:
+		if len(g.input) > 0 {
+			g.input = g.input[:len(g.input)-1]
+		}
+	case ev.Key == termbox.KeySpace:
+		g.input = append(g.input, ' ')
+	case ev.Ch != 0:
+		g.input = append(g.input, ev.Ch)
+	}
+	return true
+}
+
+// PromptFind will ask for text to search for. Tab switches between text and word search.
+func (g *GCodeViewer) PromptFind() {
+	g.prompt = viewerPromptFind
+	g.input = []rune(g.query)
+}
+
+// PromptGoTo will ask for a line number to show.
+func (g *GCodeViewer) PromptGoTo() {
+	g.prompt = viewerPromptGoTo
+	g.input = nil
+}
+
+func (g *GCodeViewer) goTo(s string) {
+	ln, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || ln < 1 || ln > len(g.Lines) {
+		g.msg = "Invalid line number: " + s
+		return
+	}
+	g.GoTo(ln)
+}
+
+// GoTo will move the cursor to the line number ln and show it.
+func (g *GCodeViewer) GoTo(ln int) {
+	g.msg = ""
+	g.Follow = false
+	g.Cursor = ln
+	g.Top = ln - 1 - g.h/3
+}
+
+// Find will search for the next line matching query, after the cursor.
+func (g *GCodeViewer) Find(query string) bool {
+	g.query = query
+	return g.FindNext()
+}
+
+// FindNext will move the cursor to the next line matching the last search, wrapping
+// around at the end.
+func (g *GCodeViewer) FindNext() bool {
+	if strings.TrimSpace(g.query) == "" {
+		return false
+	}
+	match, err := g.matcher()
+	if err != nil {
+		g.msg = "Invalid search: " + err.Error()
+		return false
+	}
+	return g.next(match, "Not found: "+g.query)
+}
+
+// NextError will move the cursor to the next line with an error, wrapping around at the end.
+func (g *GCodeViewer) NextError() bool {
+	return g.next(func(i int) bool { return g.Errors[i+1] != nil }, "No errors")
+}
+
+func (g *GCodeViewer) next(match func(i int) bool, notFound string) bool {
+	for n := 0; n < len(g.Lines); n++ {
+		// Cursor is a line number, so it is the index of the line after it
+		i := (g.Cursor + n) % len(g.Lines)
+		if match(i) {
+			g.GoTo(i + 1)
+			return true
+		}
+	}
+	g.msg = notFound
+	return false
+}
+
+// matcher returns a func reporting if the line at an index matches the query.
+func (g *GCodeViewer) matcher() (func(i int) bool, error) {
+	if !g.Words {
+		q := strings.ToUpper(strings.Join(strings.Fields(g.query), ""))
+		return func(i int) bool {
+			return strings.Contains(g.Lines[i].String(), q)
+		}, nil
+	}
+
+	b, err := gcode.ParseLine(g.query)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int) bool {
+		for _, w := range b.Line {
+			if !hasWord(g.Lines[i], w) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func hasWord(l gcode.Line, w gcode.Word) bool {
+	for _, lw := range l {
+		if lw == w {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GCodeViewer) header(top, n int) string {
+	switch g.prompt {
+	case viewerPromptFind:
+		mode := "text"
+		if g.Words {
+			mode = "words"
+		}
+		return "Find " + mode + ": " + string(g.input) + "_"
+	case viewerPromptGoTo:
+		return "Go to line: " + string(g.input) + "_"
+	}
+	if g.msg != "" {
+		return "-- " + g.msg
+	}
+	header := "-- Showing lines " + strconv.Itoa(top+1) + "-" + strconv.Itoa(top+n) + " of " + strconv.Itoa(len(g.Lines))
+	if len(g.Errors) > 0 {
+		header += ", " + strconv.Itoa(len(g.Errors)) + " errors"
+	}
+	return header
 }
 
 func (g *GCodeViewer) Draw(r Renderer) {
 	sw, sh := r.Size()
 	x, y, w, h := StandardSize(g.X, g.Y, g.Width, g.Height, sw, sh)
-	if h < 1 {
+	if h < 1 || w < 1 {
 		return
 	}
 	if h == 1 {
@@ -61,21 +272,36 @@ func (g *GCodeViewer) Draw(r Renderer) {
 		return
 	}
 	h--
-	top := 0
-	if len(g.Lines) > h {
-		top = g.Active - h/3
+
+	// the full error of the cursor line is shown at the bottom
+	var footer []string
+	if err := g.Errors[g.Cursor]; err != nil && h > 4 {
+		footer = wrap("Line "+strconv.Itoa(g.Cursor)+": "+grbl.ErrorDescription(err), w)
+		if len(footer) > h/4 {
+			footer = footer[:h/4]
+		}
+		h -= len(footer)
+	}
+	g.h = h
+
+	if g.Follow {
+		g.Top = g.Active - h/3
 	}
-	if top < 0 {
-		top = 0
+	if g.Top > len(g.Lines)-h {
+		g.Top = len(g.Lines) - h
 	}
+	if g.Top < 0 {
+		g.Top = 0
+	}
+	top := g.Top
 	l := g.Lines[top:]
 	if h < len(l) {
 		l = l[:h]
 	}
 
 	space := strings.Repeat(" ", w)
-	header := "-- Showing lines " + strconv.Itoa(top+1) + "-" + strconv.Itoa(top+len(l)) + " of " + strconv.Itoa(len(g.Lines)) + space
-	putRunes(r, x, y, []rune(header))
+	header := []rune(g.header(top, len(l)) + space)
+	putRunes(r, x, y, header[:w])
 	y++
 
 	for i, line := range l {
@@ -90,9 +316,26 @@ func (g *GCodeViewer) Draw(r Renderer) {
 		} else {
 			state = gcodeStateReady
 		}
-		renderGcode(r, x, y+i, w, line, state)
+		renderGcode(r, x, y+i, w, line, state, g.Errors[ln], ln == g.Cursor)
 	}
 	for i := len(l); i < h; i++ {
 		putRunes(r, x, y+i, []rune(space))
 	}
+	for i, s := range footer {
+		putRunesA(r, x, y+h+i, []rune(s + space)[:w], termbox.ColorRed, 0)
+	}
+}
+
+// wrap will split s into lines of at most w runes. It returns s unchanged if w is less than 1.
+func wrap(s string, w int) []string {
+	if w < 1 {
+		return []string{s}
+	}
+	rs := []rune(s)
+	var res []string
+	for len(rs) > w {
+		res = append(res, string(rs[:w]))
+		rs = rs[w:]
+	}
+	return append(res, string(rs))
 }
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mastercactapus/gg/gcode"
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestGCodeViewer_Find(t *testing.T) {
+	blocks, err := gcode.Parse(bytes.NewBufferString(`
+G21 G90
+T1 M6
+M3 S10000
+G0 X10 Y2
+G1 Z-1 F100
+M68 E0 Q1
+T2 M6
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	g := &GCodeViewer{Lines: gcode.Lines(blocks), Errors: map[int]error{5: errors.New("bad")}}
+
+	if !g.Find("m6") || g.Cursor != 2 {
+		t.Errorf("Cursor = %d; want 2", g.Cursor)
+	}
+	if !g.FindNext() || g.Cursor != 6 {
+		t.Errorf("Cursor = %d; want 6", g.Cursor)
+	}
+
+	g.Words = true
+	if !g.FindNext() || g.Cursor != 7 {
+		t.Errorf("Cursor = %d; want 7", g.Cursor)
+	}
+	if !g.FindNext() || g.Cursor != 2 {
+		t.Errorf("Cursor = %d; want 2 (wrapped)", g.Cursor)
+	}
+	if g.Find("X1") || g.Cursor != 2 {
+		t.Errorf("Cursor = %d; want 2 (not found)", g.Cursor)
+	}
+
+	if !g.NextError() || g.Cursor != 5 {
+		t.Errorf("Cursor = %d; want 5", g.Cursor)
+	}
+
+	g.PromptGoTo()
+	for _, ch := range "4" {
+		g.OnKey(termbox.Event{Ch: ch})
+	}
+	g.OnKey(termbox.Event{Key: termbox.KeyEnter})
+	if g.Cursor != 4 || g.Follow {
+		t.Errorf("Cursor = %d, Follow = %t; want 4, false", g.Cursor, g.Follow)
+	}
+	if g.OnKey(termbox.Event{Ch: 'x'}) {
+		t.Error("OnKey = true; want false without a prompt")
+	}
+}
+
+func TestGCodeViewer_DrawNarrow(t *testing.T) {
+	g := &GCodeViewer{
+		Lines:  []gcode.Line{{{Type: 'N', Value: 1}, {Type: 'G', Value: 0}}},
+		Errors: map[int]error{1: errors.New("bad")},
+		X:      1,
+		Cursor: 1,
+	}
+	ts := &testS{SizeW: 0, SizeH: 10}
+	// must not panic when there is no room for the viewer
+	g.Draw(newBoundedRenderer(ts, Rect{Right: 0, Bottom: 10}))
+
+	if l := wrap("abc", 0); len(l) != 1 || l[0] != "abc" {
+		t.Errorf("wrap(w=0) = %q; want [abc]", l)
+	}
+}
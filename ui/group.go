@@ -31,6 +31,30 @@ func (g *Group) OnClick(x, y int) {
 	}
 }
 
+func (g *Group) OnScroll(x, y int, e EventType) {
+	for _, c := range g.rendered {
+		sc, ok := c.c.(Scrollable)
+		if !ok {
+			continue
+		}
+		if !c.r.Contains(x+g.X, y+g.Y) {
+			continue
+		}
+		sx, sy := c.r.Translate(x+g.X, y+g.Y)
+		sc.OnScroll(sx, sy, e)
+	}
+}
+
+func (g *Group) OnKey(ev termbox.Event) bool {
+	for _, c := range g.rendered {
+		tc, ok := c.c.(Typeable)
+		if ok && tc.OnKey(ev) {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Group) Draw(r Renderer) {
 	sw, sh := r.Size()
 	x, y, w, h := StandardSize(g.X, g.Y, g.Width, g.Height, sw, sh)
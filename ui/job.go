@@ -1,12 +1,18 @@
 package ui
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"regexp"
 	"time"
 
 	"github.com/mastercactapus/gg/gcode"
 	"github.com/mastercactapus/gg/grbl"
+	joblog "github.com/mastercactapus/gg/log"
+	"github.com/mastercactapus/gg/probe"
 	"github.com/mastercactapus/gg/shuttlexpress"
 )
 
@@ -17,6 +23,10 @@ var jogSteps = []float64{0.001, 0.01, 0.1, 1, 10}
 const jogStepIncr = -1
 const jogStepDecr = -2
 
+// commandTimeout is the longest to wait for Grbl to respond to a command from the UI (e.g. setting
+// work zero), so an unresponsive controller can't freeze it.
+const commandTimeout = 5 * time.Second
+
 const (
 	actionCheckCode action = iota
 	actionRunJob
@@ -36,20 +46,89 @@ type JobUI struct {
 
 	checked bool
 	v       GCodeViewer
+	tp      *Toolpath
 	jogStep float64
 
+	// jog is the current continuous jog, if any.
+	jog            *jogger
+	lastJogKey     byte
+	lastJogKeyTime time.Time
+
 	recv         chan grbl.Response
 	s            grbl.Status
 	settings     grbl.Settings
-	recvStatus   chan grbl.Status
+	params       grbl.Parameters
+	info         grbl.Info
+	recvInfo     chan grbl.Info
+	estimate     grbl.Estimate
+	recvStatus   <-chan grbl.Status
 	recvSettings chan grbl.Settings
+	recvParams   chan *grbl.Parameters
+	recvZero     chan *grbl.Parameters
 	checkStatus  chan gcodeStatus
 	jobStatus    chan gcodeStatus
 
+	// active, sent and lineErrs are the progress of the job or check, copied to v by render.
+	active, sent int
+	lineErrs     map[int]error
+
 	setJogStep chan float64
 	jogStepCh  chan byte
 	zeroAxis   chan byte
 	goZeroAxis chan byte
+	selectWCS  chan int
+	clearWCS   chan int
+	probeZCh   chan struct{}
+	probeCh    chan probeRoutine
+
+	// touchPlate is the thickness of the touch plate used to probe Z zero, in mm.
+	touchPlate float64
+	probeCfg   probe.Config
+	probing    bool
+	probeText  string
+	probeDone  chan string
+
+	// heightMap is the measured work surface, used to level the job when level is set.
+	heightMap     *gcode.HeightMap
+	level         bool
+	heightMapCh   chan struct{}
+	recvHeightMap chan *gcode.HeightMap
+	levelCh       chan bool
+
+	// toolChangeZ is the machine Z position to retract to for tool changes.
+	toolChangeZ        float64
+	toolChange         *toolChange
+	toolChangeCh       chan *toolChange
+	toolChangeActionCh chan toolChangeAction
+	toolChangeResp     chan toolChangeAction
+
+	// restoreZero is the work zero from a resumed job, until it is restored or discarded.
+	restoreZero   []float64
+	restoreZeroCh chan restoreAction
+
+	// resumeFrom is the index of the line a resumed job will continue from, or 0 to run from the start.
+	resumeFrom    int
+	resumeDiscard chan struct{}
+
+	// conn is the state of the connection to Grbl. A job running when the connection is lost is
+	// interrupted until the operator confirms how to continue, and ackedLine is the last line
+	// acknowledged by Grbl.
+	conn        grbl.ConnectionState
+	connCh      chan grbl.ConnectionState
+	jobRunning  bool
+	interrupted bool
+	ackedLine   int
+	reconnectCh chan bool
+
+	// travelErr describes why the last run was blocked by the travel check.
+	travelErr string
+
+	keymap Keymap
+	help   bool
+	keyCh  chan KeyAction
+
+	// viewActions are the key actions for the G-code viewer, performed by render.
+	viewActions []KeyAction
 
 	actionCh chan action
 	renderCh chan struct{}
@@ -61,47 +140,95 @@ type JobUI struct {
 	shuttleRing      int
 	shuttleAxis      byte
 
-	l *Logger
+	l  *Logger
+	jl *joblog.Writer
 }
 
-func NewJobUI(c *grbl.Grbl, g []gcode.Line) (*JobUI, error) {
+type restoreAction int
+
+const (
+	restoreDiscard restoreAction = iota
+	restoreApply
+	restoreHomeApply
+)
+
+// NewJobUI creates a new JobUI to run the program g. Job events (e.g. setting work zero) are recorded to jl.
+func NewJobUI(c *grbl.Grbl, g []gcode.Line, jl *joblog.Writer) (*JobUI, error) {
 	l := &Logger{}
 	s := shuttlexpress.NewDevice(log.New(l, "ShuttleXpress: ", 0))
 	log.SetOutput(l)
 	log.SetFlags(0)
 	c.SetLogger(log.New(l, "Grbl: ", 0))
+	// the UI runs until exit, so never unsubscribes
+	status, _ := c.Subscribe()
 	j := &JobUI{
 		c:            c,
 		g:            g,
 		renderCh:     make(chan struct{}),
 		actionCh:     make(chan action, 1),
-		recvStatus:   c.Status(),
+		recvStatus:   status,
 		recvSettings: c.Settings(),
+		recvParams:   make(chan *grbl.Parameters),
+		recvInfo:     make(chan grbl.Info),
+		recvZero:     make(chan *grbl.Parameters),
 		checkStatus:  make(chan gcodeStatus),
 		jobStatus:    make(chan gcodeStatus),
+		lineErrs:     make(map[int]error),
 		setJogStep:   make(chan float64),
 		jogStep:      0.01,
 		jogStepCh:    make(chan byte),
 		goZeroAxis:   make(chan byte),
 		zeroAxis:     make(chan byte),
+		selectWCS:    make(chan int),
+		clearWCS:     make(chan int),
+		probeZCh:     make(chan struct{}),
+		probeCh:      make(chan probeRoutine),
+		probeCfg:     probe.DefaultConfig(),
+		probeDone:    make(chan string),
+
+		heightMapCh:   make(chan struct{}),
+		recvHeightMap: make(chan *gcode.HeightMap),
+		levelCh:       make(chan bool),
+
+		toolChangeZ:        -1,
+		toolChangeCh:       make(chan *toolChange),
+		toolChangeActionCh: make(chan toolChangeAction),
+		toolChangeResp:     make(chan toolChangeAction, 1),
+
+		keymap: DefaultKeymap(),
+		keyCh:  make(chan KeyAction, 1),
+
+		restoreZeroCh: make(chan restoreAction),
+		resumeDiscard: make(chan struct{}),
+
+		connCh:      c.Connection(),
+		reconnectCh: make(chan bool),
 
 		shuttleEvents: s.Events(),
 
-		l: l,
+		l:  l,
+		jl: jl,
 	}
 	for i, l := range j.g {
 		j.g[i] = append(gcode.Line{gcode.Word{Type: 'N', Value: float64(i + 1)}}, l...)
 	}
+	tp, err := NewToolpath(j.g)
+	if err != nil {
+		log.Println("toolpath preview:", err)
+	}
+	j.tp = tp
 	ui, err := NewUI(j.render)
 	if err != nil {
 		return nil, err
 	}
 	j.ui = ui
+	ui.OnKey = j.onKey
 
 	j.v = GCodeViewer{
-		Lines: j.g,
-		Y:     6,
-		X:     1,
+		Lines:  j.g,
+		Y:      6,
+		X:      1,
+		Follow: true,
 	}
 
 	go j.loop()
@@ -112,6 +239,32 @@ func (j *JobUI) renderSync() {
 	<-j.renderCh
 }
 
+// syncViewer will copy the job progress to the viewer, which is drawn after the loop continues,
+// and perform the queued viewer actions. It must only be called by render, while the loop is waiting.
+func (j *JobUI) syncViewer() {
+	j.v.Active, j.v.Sent = j.active, j.sent
+	j.v.Errors = make(map[int]error, len(j.lineErrs))
+	for ln, err := range j.lineErrs {
+		j.v.Errors[ln] = err
+	}
+
+	for _, a := range j.viewActions {
+		switch a {
+		case KeyFind:
+			j.v.PromptFind()
+		case KeyFindNext:
+			j.v.FindNext()
+		case KeyGoToLine:
+			j.v.PromptGoTo()
+		case KeyNextError:
+			j.v.NextError()
+		case KeyFollow:
+			j.v.Follow = !j.v.Follow
+		}
+	}
+	j.viewActions = j.viewActions[:0]
+}
+
 func (j *JobUI) loop() {
 	t := time.NewTicker(time.Millisecond * 100)
 	defer t.Stop()
@@ -121,22 +274,83 @@ func (j *JobUI) loop() {
 		select {
 		case s := <-j.recvSettings:
 			j.settings = s
+			j.estimate = grbl.EstimateGCode(s, j.g)
+			j.refreshParams()
+			j.refreshInfo()
+		case j.info = <-j.recvInfo:
 		case e := <-j.shuttleEvents:
 			j.handleShuttleEvent(e)
 		case w := <-j.zeroAxis:
+			axes := gcode.Line{{Type: w}}
 			if w == '_' {
-				j.c.ExecLine(gcode.Line{
-					gcode.Word{Type: 'G', Value: 92},
-					gcode.Word{Type: 'X'},
-					gcode.Word{Type: 'Y'},
-					gcode.Word{Type: 'Z'},
-				})
+				axes = gcode.Line{{Type: 'X'}, {Type: 'Y'}, {Type: 'Z'}}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			err := j.c.SetWorkZeroContext(ctx, 0, axes)
+			cancel()
+			if err != nil {
+				log.Println("set work zero:", err)
+			}
+			j.updateZero()
+		case wcs := <-j.selectWCS:
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			err := j.c.SelectWCSContext(ctx, wcs)
+			cancel()
+			if err != nil {
+				log.Println("select WCS:", err)
+			}
+			j.updateZero()
+		case wcs := <-j.clearWCS:
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			err := j.c.SetWorkOffsetContext(ctx, wcs-53, gcode.Line{{Type: 'X'}, {Type: 'Y'}, {Type: 'Z'}})
+			cancel()
+			if err != nil {
+				log.Println("clear WCS:", err)
+			}
+			j.updateZero()
+		case <-j.probeZCh:
+			j.performProbeZ()
+		case r := <-j.probeCh:
+			j.performProbe(r)
+		case msg := <-j.probeDone:
+			j.probing = false
+			if msg != "" {
+				j.probeText = msg
+			}
+			j.updateZero()
+		case <-j.heightMapCh:
+			j.performHeightMap()
+		case h := <-j.recvHeightMap:
+			j.probing = false
+			j.heightMap = h
+			j.level = true
+			j.probeText = heightMapText(h)
+		case v := <-j.levelCh:
+			j.level = v && j.heightMap != nil
+		case tc := <-j.toolChangeCh:
+			if j.interrupted {
+				j.toolChangeResp <- toolChangeStop
 				continue
 			}
-			j.c.ExecLine(gcode.Line{
-				gcode.Word{Type: 'G', Value: 92},
-				gcode.Word{Type: w},
-			})
+			j.toolChange = tc
+		case a := <-j.toolChangeActionCh:
+			if j.toolChange != nil {
+				j.toolChange = nil
+				j.toolChangeResp <- a
+			}
+		case p := <-j.recvParams:
+			j.params = *p
+		case p := <-j.recvZero:
+			j.params = *p
+			j.logZero()
+		case a := <-j.restoreZeroCh:
+			j.performRestoreZero(a)
+		case <-j.resumeDiscard:
+			j.resumeFrom = 0
+		case st := <-j.connCh:
+			j.handleConnection(st)
+		case v := <-j.reconnectCh:
+			j.confirmReconnect(v)
 		case w := <-j.goZeroAxis:
 			j.s.State = grbl.StateJog
 			if w == '_' {
@@ -155,11 +369,7 @@ func (j *JobUI) loop() {
 				continue
 			}
 			if w == 'H' {
-				j.s.State = grbl.StateHome
-				go func() {
-					j.c.Home()
-					j.c.Settings()
-				}()
+				j.home()
 				continue
 			}
 			j.c.Jog(gcode.Line{
@@ -168,47 +378,48 @@ func (j *JobUI) loop() {
 				gcode.Word{Type: 'F', Value: 10000},
 			})
 		case w := <-j.jogStepCh:
-			j.s.State = grbl.StateJog
-			var m gcode.Word
-			if w > 'a' {
-				m.Type = w - 32
-				m.Value = -j.jogStep
-			} else {
-				m.Type = w
-				m.Value = j.jogStep
-			}
-
-			j.c.Jog(gcode.Line{
-				gcode.Word{Type: 'G', Value: 91},
-				gcode.Word{Type: 'G', Value: 21},
-				m,
-				gcode.Word{Type: 'F', Value: 10000},
-			})
+			j.jogBy(w)
 		case v := <-j.setJogStep:
-			j.jogStep = v
+			j.jogStep = nextJogStep(j.jogStep, v)
+		case a := <-j.keyCh:
+			j.handleKeyAction(a)
 		case j.renderCh <- struct{}{}:
 			j.renderCh <- struct{}{}
 			continue
 		case <-t.C:
 			j.c.Status()
+			j.feedJog()
 		case stat := <-j.jobStatus:
+			if stat.err != nil {
+				j.lineErrs[stat.line] = stat.err
+			}
+			if errors.Is(stat.err, grbl.ErrConnectionLost) {
+				j.interrupted = true
+			} else if stat.err == nil && !stat.complete {
+				j.ackedLine = stat.line
+			}
 			if stat.complete {
-				j.v.Active = -1
-				j.v.Sent = -1
+				j.jobRunning = false
+				j.active = -1
+				j.sent = -1
 				continue
 			}
-			j.v.Active = stat.line - 16
-			j.v.Sent = stat.line
+			// the line being run is behind the acknowledged lines filling the planner
+			j.active = stat.line - j.s.Buffers.PlannerBlocks - 1
+			j.sent = stat.line
 		case check := <-j.checkStatus:
+			if check.err != nil {
+				j.lineErrs[check.line] = check.err
+			}
 			if check.complete {
-				j.v.Active = -1
-				j.v.Sent = -1
+				j.active = -1
+				j.sent = -1
 				j.s.State = ""
 				j.checked = true
 				continue
 			}
-			j.v.Active = check.line
-			j.v.Sent = check.line
+			j.active = check.line
+			j.sent = check.line
 		case j.s = <-j.recvStatus:
 		case a := <-j.actionCh:
 			j.handleAction(a)
@@ -217,6 +428,156 @@ func (j *JobUI) loop() {
 	}
 }
 
+// refreshInfo will read the version and capabilities (e.g. laser mode may have changed) in the background.
+func (j *JobUI) refreshInfo() {
+	go func() {
+		j.recvInfo <- j.c.Info()
+	}()
+}
+
+// refreshParams will read the coordinate system offsets in the background.
+func (j *JobUI) refreshParams() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		p, err := j.c.ParametersContext(ctx)
+		if err != nil {
+			log.Println("read parameters:", err)
+			return
+		}
+		j.recvParams <- p
+	}()
+}
+
+// updateZero will read the coordinate system offsets in the background, and log the new work zero.
+func (j *JobUI) updateZero() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		p, err := j.c.ParametersContext(ctx)
+		if err != nil {
+			log.Println("read parameters:", err)
+			return
+		}
+		j.recvZero <- p
+	}()
+}
+
+// workZero returns the current work zero in machine coordinates, or nil if unknown.
+func (j *JobUI) workZero() []float64 {
+	if j.params.ActiveWCS == 0 {
+		return nil
+	}
+	off := j.params.Offset(j.params.ActiveWCS)
+	return []float64{
+		off[0] + j.params.G92[0],
+		off[1] + j.params.G92[1],
+		off[2] + j.params.G92[2],
+	}
+}
+
+func (j *JobUI) logZero() {
+	zero := j.workZero()
+	if zero == nil {
+		return
+	}
+	err := j.jl.Coordinates("ZERO", zero)
+	if err != nil {
+		log.Println("failed to log work zero:", err)
+	}
+}
+
+// OfferRestoreZero will prompt to restore the work zero of a resumed job.
+// It must be called before Start.
+func (j *JobUI) OfferRestoreZero(zero []float64) {
+	j.restoreZero = zero
+}
+
+// OfferResume will continue the job after the last line acknowledged by Grbl (by line number)
+// when it is next run. Lines that may not have left the planner are run again.
+// It must be called before Start.
+func (j *JobUI) OfferResume(acked int) {
+	j.resumeFrom = acked - j.c.Buffers().PlannerBlocks
+	if j.resumeFrom < 0 || j.resumeFrom >= len(j.g) {
+		j.resumeFrom = 0
+	}
+}
+
+func (j *JobUI) performRestoreZero(a restoreAction) {
+	zero := j.restoreZero
+	j.restoreZero = nil
+	if a == restoreDiscard || len(zero) < 3 {
+		return
+	}
+
+	go func() {
+		if a == restoreHomeApply {
+			j.c.Home()
+		}
+		// saved coordinates are always in mm
+		err := j.c.ExecLine(gcode.Line{{Type: 'G', Value: 21}})
+		if err == nil {
+			err = j.c.SetWorkOffset(0, gcode.Line{
+				{Type: 'X', Value: zero[0]},
+				{Type: 'Y', Value: zero[1]},
+				{Type: 'Z', Value: zero[2]},
+			})
+		}
+		if err != nil {
+			log.Println("restore work zero:", err)
+		}
+		j.updateZero()
+	}()
+}
+
+// home will run the homing cycle, then refresh the settings.
+func (j *JobUI) home() {
+	j.s.State = grbl.StateHome
+	go func() {
+		j.c.Home()
+		j.c.Settings()
+	}()
+}
+
+// jogBy will jog one step along an axis, positive for upper case (e.g. 'X') and negative for lower case.
+func (j *JobUI) jogBy(w byte) {
+	j.s.State = grbl.StateJog
+	var m gcode.Word
+	if w > 'a' {
+		m.Type = w - 32
+		m.Value = -j.jogStep
+	} else {
+		m.Type = w
+		m.Value = j.jogStep
+	}
+
+	j.c.Jog(gcode.Line{
+		gcode.Word{Type: 'G', Value: 91},
+		gcode.Word{Type: 'G', Value: 21},
+		m,
+		gcode.Word{Type: 'F', Value: 10000},
+	})
+}
+
+// nextJogStep returns the jog step to use for v, which is a step or jogStepIncr/jogStepDecr.
+func nextJogStep(cur, v float64) float64 {
+	for i, s := range jogSteps {
+		if math.Abs(s-cur) > s/100 {
+			continue
+		}
+		switch {
+		case v == jogStepIncr && i < len(jogSteps)-1:
+			return jogSteps[i+1]
+		case v == jogStepDecr && i > 0:
+			return jogSteps[i-1]
+		}
+	}
+	if v < 0 {
+		return cur
+	}
+	return v
+}
+
 func (j *JobUI) JogStep(a byte) {
 	select {
 	case j.jogStepCh <- a:
@@ -237,24 +598,81 @@ func (j *JobUI) handleAction(a action) {
 	}
 }
 func (j *JobUI) performRun() {
-	resp := j.c.RunGCode(j.g)
-	go func() {
-		ln := 1
-		for stat := range resp {
-			j.jobStatus <- gcodeStatus{
-				line: ln,
-				err:  stat.Err,
-			}
-			ln++
+	if j.interrupted {
+		// the operator must first choose how to continue the interrupted job
+		return
+	}
+	j.lineErrs = make(map[int]error)
+
+	prog := j.g
+	ln := j.resumeFrom
+	if j.resumeFrom > 0 {
+		pre, err := gcode.Resume(j.g, j.resumeFrom)
+		if err != nil {
+			log.Println("failed to resume:", err)
+			return
+		}
+		prog = append(pre, j.g[j.resumeFrom+1:]...)
+	}
+	if j.level && j.heightMap != nil {
+		var err error
+		prog, err = j.heightMap.Level(prog, levelSegment)
+		if err != nil {
+			log.Println("failed to level job:", err)
+			return
 		}
+	}
+	if !j.checkTravel(prog) {
+		return
+	}
+
+	err := j.jl.Comment("Start job")
+	if err != nil {
+		log.Println("failed to write log:", err)
+	}
+	j.logZero()
+	if j.resumeFrom > 0 {
+		err = j.jl.Comment(fmt.Sprintf("Resume job at line %d", j.resumeFrom+1))
+		if err != nil {
+			log.Println("failed to write log:", err)
+		}
+		j.resumeFrom = 0
+	}
+	j.jobRunning = true
+	j.ackedLine = ln
+
+	go func() {
+		j.runJob(prog, ln)
 		j.jobStatus <- gcodeStatus{complete: true}
 	}()
 }
+
+// logResult records a line sent to Grbl, along with its response.
+func (j *JobUI) logResult(l gcode.Line, err error) {
+	var resp string
+	var e grbl.Error
+	switch {
+	case err == nil:
+		resp = "ok"
+	case errors.As(err, &e):
+		resp = fmt.Sprintf("error:%d", e)
+	}
+
+	logErr := j.jl.SerialSend(l.String())
+	if logErr == nil && resp != "" {
+		// the line was not processed by Grbl (e.g. soft reset) if there is no response
+		logErr = j.jl.SerialRecv(resp)
+	}
+	if logErr != nil {
+		log.Println("failed to write log:", logErr)
+	}
+}
+
 func (j *JobUI) performStop() {
 	switch j.s.State {
 	case grbl.StateCheck:
-		j.v.Active = 0
-		j.v.Sent = 0
+		j.active = 0
+		j.sent = 0
 		j.c.SoftReset()
 	case grbl.StateJog:
 		j.c.JogCancel()
@@ -285,8 +703,52 @@ func (j *JobUI) status() Control {
 	switch {
 	default:
 		return &Text{X: 1, Y: 3, Lines: []string{"No job running.", ""}}
+	case j.interrupted || j.conn != grbl.ConnectionSuccess:
+		return j.connectionStatus()
+	case j.toolChange != nil:
+		return j.toolChangeStatus()
+	case j.travelErr != "" && j.s.State == grbl.StateIdle:
+		return &Group{
+			X: 1, Y: 3, Height: 3,
+			Width: -1,
+			Title: "Job exceeds machine travel",
+			Clear: true,
+			Controls: []Control{
+				&Text{X: 1, Lines: []string{j.travelErr}},
+			},
+		}
+	case j.restoreZero != nil && (j.s.State == grbl.StateIdle || j.s.State == grbl.StateAlarm):
+		return &Group{
+			X: 1, Y: 3, Height: 3,
+			Width: -1,
+			Title: fmt.Sprintf("Restore Zero X%.3f Y%.3f Z%.3f", j.restoreZero[0], j.restoreZero[1], j.restoreZero[2]),
+			Clear: true,
+			Controls: []Control{
+				&Button{X: 1, Text: "Home+Set", Enabled: true,
+					OnClickFunc: func(int, int) { j.restoreZeroCh <- restoreHomeApply },
+				},
+				&Button{X: 14, Text: "Set", Enabled: j.s.State == grbl.StateIdle,
+					OnClickFunc: func(int, int) { j.restoreZeroCh <- restoreApply },
+				},
+				&Button{X: 22, Text: "Discard", Enabled: true,
+					OnClickFunc: func(int, int) { j.restoreZeroCh <- restoreDiscard },
+				},
+			},
+		}
 	case !j.checked && j.s.State == grbl.StateIdle:
 		return &Text{X: 1, Y: 3, Lines: []string{"Perform 'Check' to get started.", ""}}
+	case j.resumeFrom > 0 && j.s.State == grbl.StateIdle:
+		return &Group{
+			X: 1, Y: 3, Height: 3,
+			Width: -1,
+			Title: fmt.Sprintf("Run will resume at line %d", j.resumeFrom+1),
+			Clear: true,
+			Controls: []Control{
+				&Button{X: 1, Text: "Run From Start", Enabled: true,
+					OnClickFunc: func(int, int) { j.resumeDiscard <- struct{}{} },
+				},
+			},
+		}
 	case j.s.State == grbl.StateCheck:
 		return &Progress{
 			X:     1,
@@ -294,7 +756,7 @@ func (j *JobUI) status() Control {
 			Width: -1,
 			Title: "Checking...",
 			Max:   len(j.v.Lines),
-			Value: j.v.Active - 1,
+			Value: j.active - 1,
 		}
 	case j.checked && j.s.State == grbl.StateRun:
 		return &Text{
@@ -308,7 +770,7 @@ func (j *JobUI) status() Control {
 		return &Group{
 			X: 1, Y: 3, Height: 3,
 			Width: -1,
-			Title: "Alarm Mode",
+			Title: j.alarmTitle(),
 			Clear: true,
 			Controls: []Control{
 				&Button{X: 1, Text: "Home", Enabled: true,
@@ -339,6 +801,7 @@ func (j *JobUI) status() Control {
 
 func (j *JobUI) performCheck() {
 	j.checked = false
+	j.lineErrs = make(map[int]error)
 	resp := j.c.CheckGCode(j.g)
 	go func() {
 		ln := 1
@@ -354,17 +817,39 @@ func (j *JobUI) performCheck() {
 }
 
 func (j *JobUI) duration() time.Duration {
-	return time.Minute + time.Second*30
+	return j.estimate.Total().Round(time.Second)
 }
 func (j *JobUI) remaining() time.Duration {
-	return time.Second * 19
+	line := j.s.Line
+	if line == 0 {
+		// Grbl was built without line number reporting
+		line = j.active
+	}
+	return j.estimate.Remaining(line).Round(time.Second)
+}
+
+func (j *JobUI) alarmTitle() string {
+	if j.s.Alarm == 0 {
+		return "Alarm Mode"
+	}
+	return "Alarm Mode -- " + grbl.ErrorDescription(j.s.Alarm)
 }
 
 func (j *JobUI) machineStatusText() string {
+	if j.conn != grbl.ConnectionSuccess {
+		return "Machine Status -- " + j.conn.String()
+	}
 	if j.s.State == grbl.StateUnknown {
 		return "Machine Status -- Connecting"
 	}
-	return "Machine Status -- " + string(j.s.State)
+	title := "Machine Status -- " + string(j.s.State)
+	if j.info.Version != "" {
+		title += " -- Grbl " + j.info.Version
+	}
+	if j.info.LaserMode {
+		title += " (laser)"
+	}
+	return title
 }
 
 func (j *JobUI) Start() error {
@@ -1,6 +1,9 @@
 package ui
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/mastercactapus/gg/grbl"
 	termbox "github.com/nsf/termbox-go"
 )
@@ -9,6 +12,7 @@ func (j *JobUI) render() []Control {
 	serialMode := j.c.SerialMode()
 	j.renderSync()
 	defer j.renderSync()
+	j.syncViewer()
 
 	return []Control{
 		&Group{
@@ -26,7 +30,7 @@ func (j *JobUI) render() []Control {
 					X:           16,
 					Y:           1,
 					Text:        "Run",
-					Enabled:     j.s.State == grbl.StateIdle && j.checked,
+					Enabled:     j.s.State == grbl.StateIdle && j.checked && !j.interrupted,
 					OnClickFunc: func(x, y int) { j.actionCh <- actionRunJob },
 				},
 				&Button{
@@ -36,6 +40,21 @@ func (j *JobUI) render() []Control {
 					Enabled:     j.isRunning(),
 					OnClickFunc: func(x, y int) { j.actionCh <- actionStopJob },
 				},
+				&Button{X: 1, Y: 2, Text: "Find", Enabled: true,
+					OnClickFunc: func(int, int) { j.keyCh <- KeyFind },
+				},
+				&Button{X: 8, Y: 2, Text: "Next", Enabled: true,
+					OnClickFunc: func(int, int) { j.keyCh <- KeyFindNext },
+				},
+				&Button{X: 15, Y: 2, Text: "Line", Enabled: true,
+					OnClickFunc: func(int, int) { j.keyCh <- KeyGoToLine },
+				},
+				&Button{X: 22, Y: 2, Text: "Err", Enabled: len(j.lineErrs) > 0,
+					OnClickFunc: func(int, int) { j.keyCh <- KeyNextError },
+				},
+				&Checkbox{X: 28, Y: 2, Text: "Follow", Enabled: true, Checked: j.v.Follow,
+					OnClickFunc: func(int, int, bool) { j.keyCh <- KeyFollow },
+				},
 				j.status(),
 				&j.v,
 			},
@@ -206,12 +225,41 @@ func (j *JobUI) render() []Control {
 				},
 			},
 		},
+		j.overrides(),
+		j.workCoordinates(),
+		j.probeRoutines(),
+		j.toolpath(),
 		&Group{
 			Title:    "Logs",
 			X:        40,
 			Y:        20,
+			Width:    30,
 			Controls: []Control{j.l},
 		},
+		j.keyHelp(),
+	}
+}
+
+func (j *JobUI) toolpath() Control {
+	j.tp.Active = j.active
+	j.tp.Pos = j.s.WPos
+	j.tp.Y = 2
+	return &Group{
+		Title:  "Toolpath",
+		X:      70,
+		Y:      20,
+		Width:  30,
+		Height: 20,
+		Controls: []Control{
+			&Button{Text: "Fit", Enabled: true,
+				OnClickFunc: func(int, int) { j.tp.Fit() },
+			},
+			&Checkbox{X: 6, Text: "XZ", Enabled: true, Checked: j.tp.Side,
+				OnClickFunc: func(_, _ int, v bool) { j.tp.Side = v },
+			},
+			&Text{Y: 1, Lines: []string{"scroll: zoom, click: center"}},
+			j.tp,
+		},
 	}
 }
 
@@ -253,3 +301,155 @@ func (j *JobUI) shuttle() Control {
 		},
 	}
 }
+
+func percent(v int) string {
+	if v == 0 {
+		return "   -"
+	}
+	return fmt.Sprintf("%3d%%", v)
+}
+
+func (j *JobUI) overrides() Control {
+	ov := j.s.FieldOverrides
+	connected := j.s.State != grbl.StateUnknown
+	coolant := j.s.State == grbl.StateIdle || j.s.State == grbl.StateRun || j.s.State == grbl.StateHoldComplete
+	// capabilities are unknown until the build info is read
+	known := j.info.Options != ""
+	spindle := connected && (j.info.VariableSpindle || !known)
+	return &Group{
+		Title:  "Overrides",
+		Width:  20,
+		Height: 14,
+		X:      100,
+		Y:      6,
+		Controls: []Control{
+			&Text{Lines: []string{"Feed:         " + percent(ov.Feed)}},
+			&Button{Y: 1, Text: "-", Enabled: connected,
+				OnClickFunc: func(int, int) { j.c.FeedOverride(-10) },
+			},
+			&Button{Y: 1, X: 5, Text: "+", Enabled: connected,
+				OnClickFunc: func(int, int) { j.c.FeedOverride(10) },
+			},
+			&Button{Y: 1, X: 10, Text: "100", Enabled: connected,
+				OnClickFunc: func(int, int) { j.c.FeedOverrideReset() },
+			},
+
+			&Text{Y: 3, Lines: []string{"Rapid:        " + percent(ov.Rapid)}},
+			&Button{Y: 4, Text: "-", Enabled: connected && ov.Rapid > 25,
+				OnClickFunc: func(int, int) { j.c.RapidOverride(ov.Rapid / 2) },
+			},
+			&Button{Y: 4, X: 5, Text: "+", Enabled: connected && ov.Rapid < 100,
+				OnClickFunc: func(int, int) { j.c.RapidOverride(ov.Rapid * 2) },
+			},
+			&Button{Y: 4, X: 10, Text: "100", Enabled: connected,
+				OnClickFunc: func(int, int) { j.c.RapidOverride(100) },
+			},
+
+			&Text{Y: 6, Lines: []string{"Spindle:      " + percent(ov.Spindle)}},
+			&Button{Y: 7, Text: "-", Enabled: spindle,
+				OnClickFunc: func(int, int) { j.c.SpindleOverride(-10) },
+			},
+			&Button{Y: 7, X: 5, Text: "+", Enabled: spindle,
+				OnClickFunc: func(int, int) { j.c.SpindleOverride(10) },
+			},
+			&Button{Y: 7, X: 10, Text: "100", Enabled: spindle,
+				OnClickFunc: func(int, int) { j.c.SpindleOverrideReset() },
+			},
+			&Button{Y: 8, Text: "Stop", Enabled: j.s.State == grbl.StateHoldComplete,
+				OnClickFunc: func(int, int) { j.c.ToggleSpindleStop() },
+			},
+
+			&Button{Y: 10, Text: "Flood", Enabled: coolant,
+				OnClickFunc: func(int, int) { j.c.ToggleFlood() },
+			},
+			&Button{Y: 10, X: 9, Text: "Mist", Enabled: coolant && (j.info.CoolantMist || !known),
+				OnClickFunc: func(int, int) { j.c.ToggleMist() },
+			},
+			&Button{Y: 11, Text: "Door", Enabled: connected,
+				OnClickFunc: func(int, int) { j.c.SafetyDoor() },
+			},
+		},
+	}
+}
+
+func (j *JobUI) workCoordinates() Control {
+	idle := j.s.State == grbl.StateIdle
+	active := j.params.ActiveWCS
+
+	var controls []Control
+	for i := 0; i < 6; i++ {
+		wcs := 54 + i
+		controls = append(controls, &Checkbox{
+			X:           (i % 3) * 6,
+			Y:           i / 3,
+			Text:        "G" + strconv.Itoa(wcs),
+			Radio:       true,
+			Enabled:     idle,
+			Checked:     active == wcs,
+			OnClickFunc: func(int, int, bool) { j.selectWCS <- wcs },
+		})
+	}
+
+	off := j.params.Offset(active)
+	controls = append(controls,
+		&Text{Y: 3, Lines: []string{
+			fmt.Sprintf("X %12.3f", off[0]),
+			fmt.Sprintf("Y %12.3f", off[1]),
+			fmt.Sprintf("Z %12.3f", off[2]),
+		}},
+		&Button{Y: 7, Text: "Clear", Enabled: idle && active != 0,
+			OnClickFunc: func(int, int) { j.clearWCS <- active },
+		},
+		&Button{X: 8, Y: 7, Text: "Probe Z", Enabled: idle && active != 0 && !j.probing,
+			OnClickFunc: func(int, int) { j.probeZCh <- struct{}{} },
+		},
+	)
+
+	return &Group{
+		Title:    "Work Offsets",
+		Width:    20,
+		Height:   10,
+		X:        100,
+		Y:        20,
+		Controls: controls,
+	}
+}
+
+func (j *JobUI) probeRoutines() Control {
+	enabled := j.s.State == grbl.StateIdle && j.params.ActiveWCS != 0 && !j.probing
+	button := func(x, y int, text string, r probeRoutine) Control {
+		return &Button{X: x, Y: y, Text: text, Enabled: enabled,
+			OnClickFunc: func(int, int) { j.probeCh <- r },
+		}
+	}
+	return &Group{
+		Title:  "Probe",
+		Width:  20,
+		Height: 9,
+		X:      100,
+		Y:      30,
+		Controls: []Control{
+			&Text{Lines: []string{"Corner"}},
+			button(0, 1, "↖", probeCornerBL),
+			button(6, 1, "↗", probeCornerBR),
+			button(0, 2, "↙", probeCornerFL),
+			button(6, 2, "↘", probeCornerFR),
+			button(12, 1, "Bore", probeBore),
+			button(12, 2, "Boss", probeBoss),
+			button(0, 3, "Top", probeSurface),
+			&Text{X: 6, Y: 3, Lines: []string{"Height past"}},
+			button(0, 4, "X-", probeHeightXMinus),
+			button(4, 4, "X+", probeHeightXPlus),
+			button(8, 4, "Y-", probeHeightYMinus),
+			button(12, 4, "Y+", probeHeightYPlus),
+			&Button{Y: 5, Text: "Map", Enabled: enabled,
+				OnClickFunc: func(int, int) { j.heightMapCh <- struct{}{} },
+			},
+			&Checkbox{X: 6, Y: 5, Text: "Level", Enabled: j.heightMap != nil && !j.isRunning(),
+				Checked:     j.level,
+				OnClickFunc: func(_, _ int, v bool) { j.levelCh <- v },
+			},
+			&Text{Y: 6, Lines: []string{j.probeText}},
+		},
+	}
+}
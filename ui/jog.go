@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"log"
+	"time"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl"
+)
+
+const (
+	// keyRepeatDelay is the longest time between presses of a jog key for it to be
+	// treated as held (i.e. key repeat) rather than a single step.
+	keyRepeatDelay = 600 * time.Millisecond
+
+	// keyReleaseDelay is the time after the last repeat of a held key when it is treated as released.
+	keyReleaseDelay = 200 * time.Millisecond
+
+	// keyJogSteps is the number of jog steps moved each second while a key is held.
+	keyJogSteps = 10
+)
+
+// ringSpeeds is the fraction of the max rate to jog at for each ShuttleXpress ring position.
+var ringSpeeds = []float64{0, 0.01, 0.03, 0.06, 0.12, 0.25, 0.5, 1}
+
+// jogger streams increments to jog continuously until stopped.
+type jogger struct {
+	axis byte
+	dir  float64
+	inc  grbl.JogIncrement
+
+	// blocks is the number of increments to keep queued in the planner
+	blocks int
+
+	// start and sent are used to estimate the increments left in the planner
+	start time.Time
+	sent  int
+
+	// key is set for jogs by a held key, which is released when repeats stop.
+	key     bool
+	lastKey time.Time
+}
+
+// queued returns the estimated number of increments waiting in the planner, from the time
+// since the jog started.
+func (jg *jogger) queued(now time.Time) int {
+	n := jg.sent - int(now.Sub(jg.start)/jg.inc.Time)
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// setIncrement will change the increment (e.g. for a new speed) of the following moves.
+func (jg *jogger) setIncrement(inc grbl.JogIncrement, now time.Time) {
+	jg.sent = jg.queued(now)
+	jg.start = now
+	jg.inc = inc
+}
+
+// axisIndex returns the index (0, 1 or 2) of an axis letter of either case.
+func axisIndex(w byte) int {
+	if w >= 'a' {
+		w -= 32
+	}
+	return int(w - 'X')
+}
+
+// startJog will begin continuous jogging along an axis, by letter, at rate (mm/min). Any current
+// continuous jog is stopped.
+func (j *JobUI) startJog(axis byte, dir, rate float64) *jogger {
+	if j.jog != nil {
+		j.stopJog()
+	}
+	blocks := grbl.JogBlocks(j.s.Buffers.PlannerBlocks)
+	inc, ok := j.settings.ContinuousJog(axisIndex(axis), rate, blocks)
+	if !ok {
+		log.Println("jog: max rate and acceleration are unknown")
+		return nil
+	}
+	j.s.State = grbl.StateJog
+	j.jog = &jogger{axis: axis, dir: dir, inc: inc, blocks: blocks, start: time.Now()}
+	j.feedJog()
+	return j.jog
+}
+
+// stopJog will cancel the current continuous jog.
+func (j *JobUI) stopJog() {
+	j.jog = nil
+	j.c.JogCancel()
+}
+
+// feedJog will send increments to keep the planner filled while jogging continuously. It is called
+// for each status report.
+func (j *JobUI) feedJog() {
+	jg := j.jog
+	if jg == nil {
+		return
+	}
+	now := time.Now()
+	if jg.key && now.Sub(jg.lastKey) > keyReleaseDelay {
+		j.stopJog()
+		return
+	}
+	if j.s.State != grbl.StateJog && j.s.State != grbl.StateIdle {
+		// e.g. a limit switch was hit
+		j.jog = nil
+		return
+	}
+
+	queued := jg.queued(now)
+	if j.settings.StatusReport.BufferData {
+		// the planner is slower than estimated while accelerating
+		if n := j.s.Buffers.PlannerUsed; n > queued {
+			queued = n
+		}
+	}
+	for ; queued < jg.blocks; queued++ {
+		j.c.Jog(gcode.Line{
+			{Type: 'G', Value: 91},
+			{Type: 'G', Value: 21},
+			{Type: jg.axis, Value: jg.dir * jg.inc.Distance},
+			{Type: 'F', Value: jg.inc.Rate},
+		})
+		jg.sent++
+	}
+}
+
+// keyJog handles a press of a jog key, by axis (upper case is positive). A single press moves one
+// jog step, while holding the key (i.e. repeated presses) jogs continuously.
+func (j *JobUI) keyJog(w byte) {
+	now := time.Now()
+	axis, dir := w, 1.0
+	if w >= 'a' {
+		axis, dir = w-32, -1
+	}
+	if jg := j.jog; jg != nil && jg.key && jg.axis == axis && jg.dir == dir {
+		jg.lastKey = now
+		return
+	}
+	if j.jog != nil {
+		j.stopJog()
+	}
+
+	repeat := j.lastJogKey == w && now.Sub(j.lastJogKeyTime) < keyRepeatDelay
+	j.lastJogKey, j.lastJogKeyTime = w, now
+	if !repeat {
+		j.jogBy(w)
+		return
+	}
+
+	jg := j.startJog(axis, dir, j.jogStep*keyJogSteps*60)
+	if jg != nil {
+		jg.key = true
+		jg.lastKey = now
+	}
+}
+
+// shuttleJog will jog continuously along the selected axis while the ring is deflected,
+// faster as it is turned further.
+func (j *JobUI) shuttleJog(ring int) {
+	if ring == 0 {
+		if j.jog != nil {
+			j.stopJog()
+			return
+		}
+		j.c.JogCancel()
+		return
+	}
+	if !j.shuttleConnected || j.shuttleAxis == 0 {
+		return
+	}
+	if j.s.State != grbl.StateIdle && j.s.State != grbl.StateJog {
+		return
+	}
+
+	dir := 1.0
+	if ring < 0 {
+		ring, dir = -ring, -1
+	}
+	if j.shuttleAxis == 'Z' {
+		dir = -dir
+	}
+	if ring >= len(ringSpeeds) {
+		ring = len(ringSpeeds) - 1
+	}
+	max := [3]grbl.Rate{j.settings.MaxRate.X, j.settings.MaxRate.Y, j.settings.MaxRate.Z}
+	rate := max[axisIndex(j.shuttleAxis)].MillimetersPerMinute() * ringSpeeds[ring]
+
+	if jg := j.jog; jg != nil && !jg.key && jg.axis == j.shuttleAxis && jg.dir == dir {
+		inc, ok := j.settings.ContinuousJog(axisIndex(jg.axis), rate, jg.blocks)
+		if ok {
+			jg.setIncrement(inc, time.Now())
+		}
+		return
+	}
+	j.startJog(j.shuttleAxis, dir, rate)
+}
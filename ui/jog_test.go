@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mastercactapus/gg/grbl"
+)
+
+func TestJogger_Queued(t *testing.T) {
+	start := time.Now()
+	jg := &jogger{inc: grbl.JogIncrement{Time: 50 * time.Millisecond}, start: start, sent: 7}
+
+	if n := jg.queued(start.Add(120 * time.Millisecond)); n != 5 {
+		t.Errorf("queued = %d; want 5", n)
+	}
+	if n := jg.queued(start.Add(time.Second)); n != 0 {
+		t.Errorf("queued = %d; want 0", n)
+	}
+
+	now := start.Add(100 * time.Millisecond)
+	jg.setIncrement(grbl.JogIncrement{Time: 200 * time.Millisecond}, now)
+	if n := jg.queued(now.Add(250 * time.Millisecond)); n != 4 {
+		t.Errorf("queued = %d; want 4", n)
+	}
+}
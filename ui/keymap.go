@@ -0,0 +1,273 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// KeyAction is an operation of the JobUI that can be bound to a key.
+type KeyAction string
+
+const (
+	KeyNone KeyAction = "none"
+
+	KeyJogXPlus  KeyAction = "jog-x+"
+	KeyJogXMinus KeyAction = "jog-x-"
+	KeyJogYPlus  KeyAction = "jog-y+"
+	KeyJogYMinus KeyAction = "jog-y-"
+	KeyJogZPlus  KeyAction = "jog-z+"
+	KeyJogZMinus KeyAction = "jog-z-"
+
+	KeyStep0001  KeyAction = "step-0.001"
+	KeyStep001   KeyAction = "step-0.01"
+	KeyStep01    KeyAction = "step-0.1"
+	KeyStep1     KeyAction = "step-1"
+	KeyStep10    KeyAction = "step-10"
+	KeyStepPlus  KeyAction = "step+"
+	KeyStepMinus KeyAction = "step-"
+
+	KeyCheck  KeyAction = "check"
+	KeyRun    KeyAction = "run"
+	KeyStop   KeyAction = "stop"
+	KeyHold   KeyAction = "hold"
+	KeyResume KeyAction = "resume"
+	KeyHome   KeyAction = "home"
+	KeyUnlock KeyAction = "unlock"
+
+	KeyFocusNext KeyAction = "focus-next"
+	KeyFocusPrev KeyAction = "focus-prev"
+	KeyActivate  KeyAction = "activate"
+	KeyHelp      KeyAction = "help"
+
+	KeyFind      KeyAction = "find"
+	KeyFindNext  KeyAction = "find-next"
+	KeyGoToLine  KeyAction = "goto-line"
+	KeyNextError KeyAction = "next-error"
+	KeyFollow    KeyAction = "follow"
+)
+
+// keyActions lists all actions with a description, in the order shown by the help overlay.
+var keyActions = []struct {
+	a    KeyAction
+	desc string
+}{
+	{KeyJogXPlus, "Jog X+"},
+	{KeyJogXMinus, "Jog X-"},
+	{KeyJogYPlus, "Jog Y+"},
+	{KeyJogYMinus, "Jog Y-"},
+	{KeyJogZPlus, "Jog Z+"},
+	{KeyJogZMinus, "Jog Z-"},
+	{KeyStep0001, "Jog step 0.001"},
+	{KeyStep001, "Jog step 0.01"},
+	{KeyStep01, "Jog step 0.1"},
+	{KeyStep1, "Jog step 1"},
+	{KeyStep10, "Jog step 10"},
+	{KeyStepPlus, "Larger jog step"},
+	{KeyStepMinus, "Smaller jog step"},
+	{KeyCheck, "Check"},
+	{KeyRun, "Run"},
+	{KeyStop, "Stop"},
+	{KeyHold, "Feed hold"},
+	{KeyResume, "Resume"},
+	{KeyHome, "Home"},
+	{KeyUnlock, "Unlock"},
+	{KeyFocusNext, "Focus next control"},
+	{KeyFocusPrev, "Focus previous control"},
+	{KeyActivate, "Press focused control"},
+	{KeyHelp, "Show/hide keys"},
+	{KeyFind, "Find in GCode"},
+	{KeyFindNext, "Find next"},
+	{KeyGoToLine, "Go to line"},
+	{KeyNextError, "Next error"},
+	{KeyFollow, "Follow active line"},
+}
+
+// Keymap binds keys, by name, to actions. Names are a single character (e.g. "r" or "?"),
+// a named key (e.g. "Up", "PgDn", "F1" or "Enter") or a control key (e.g. "Ctrl-R", except
+// Ctrl-C, Ctrl-L, Ctrl-I and Ctrl-M).
+type Keymap map[string]KeyAction
+
+// DefaultKeymap returns the default key bindings.
+func DefaultKeymap() Keymap {
+	return Keymap{
+		"Right": KeyJogXPlus,
+		"Left":  KeyJogXMinus,
+		"Up":    KeyJogYPlus,
+		"Down":  KeyJogYMinus,
+		"PgUp":  KeyJogZPlus,
+		"PgDn":  KeyJogZMinus,
+
+		"1": KeyStep0001,
+		"2": KeyStep001,
+		"3": KeyStep01,
+		"4": KeyStep1,
+		"5": KeyStep10,
+		"+": KeyStepPlus,
+		"-": KeyStepMinus,
+
+		"c":    KeyCheck,
+		"r":    KeyRun,
+		"s":    KeyStop,
+		"!":    KeyHold,
+		"~":    KeyResume,
+		"Home": KeyHome,
+		"u":    KeyUnlock,
+
+		"Tab":    KeyFocusNext,
+		"Ctrl-P": KeyFocusPrev,
+		"Enter":  KeyActivate,
+		"?":      KeyHelp,
+		"F1":     KeyHelp,
+
+		"/": KeyFind,
+		"n": KeyFindNext,
+		"g": KeyGoToLine,
+		"e": KeyNextError,
+		"f": KeyFollow,
+	}
+}
+
+var namedKeys = map[string]termbox.Key{
+	"F1": termbox.KeyF1, "F2": termbox.KeyF2, "F3": termbox.KeyF3, "F4": termbox.KeyF4,
+	"F5": termbox.KeyF5, "F6": termbox.KeyF6, "F7": termbox.KeyF7, "F8": termbox.KeyF8,
+	"F9": termbox.KeyF9, "F10": termbox.KeyF10, "F11": termbox.KeyF11, "F12": termbox.KeyF12,
+
+	"Insert": termbox.KeyInsert,
+	"Delete": termbox.KeyDelete,
+	"Home":   termbox.KeyHome,
+	"End":    termbox.KeyEnd,
+	"PgUp":   termbox.KeyPgup,
+	"PgDn":   termbox.KeyPgdn,
+	"Up":     termbox.KeyArrowUp,
+	"Down":   termbox.KeyArrowDown,
+	"Left":   termbox.KeyArrowLeft,
+	"Right":  termbox.KeyArrowRight,
+
+	"Tab":       termbox.KeyTab,
+	"Enter":     termbox.KeyEnter,
+	"Esc":       termbox.KeyEsc,
+	"Space":     termbox.KeySpace,
+	"Backspace": termbox.KeyBackspace2,
+}
+
+// reservedKeys can't be bound, with the reason. Ctrl-C and Ctrl-L are handled by the UI, and
+// termbox reports the rest as the named key.
+var reservedKeys = map[string]string{
+	"Ctrl-C": "quits",
+	"Ctrl-L": "redraws the screen",
+	"Ctrl-I": "is reported as Tab",
+	"Ctrl-M": "is reported as Enter",
+	"Ctrl-[": "is reported as Esc",
+}
+
+// keyName returns the name of the key for an event.
+func keyName(ev termbox.Event) string {
+	if ev.Ch != 0 {
+		return string(ev.Ch)
+	}
+	for name, k := range namedKeys {
+		if k == ev.Key {
+			return name
+		}
+	}
+	if ev.Key >= termbox.KeyCtrlA && ev.Key <= termbox.KeyCtrlZ {
+		return "Ctrl-" + string(rune('A'+ev.Key-termbox.KeyCtrlA))
+	}
+	return ""
+}
+
+func validKeyName(name string) bool {
+	if utf8.RuneCountInString(name) == 1 {
+		return true
+	}
+	if _, ok := namedKeys[name]; ok {
+		return true
+	}
+	return len(name) == 6 && strings.HasPrefix(name, "Ctrl-") && name[5] >= 'A' && name[5] <= 'Z'
+}
+
+func validKeyAction(a KeyAction) bool {
+	if a == KeyNone {
+		return true
+	}
+	for _, ka := range keyActions {
+		if ka.a == a {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseKeymap will read key bindings, one per line as the key name followed by the action
+// (e.g. "Ctrl-R run"), and return them added to the default key bindings. A key bound to
+// "none" is removed. Blank lines and lines starting with '#' are ignored.
+func ParseKeymap(r io.Reader) (Keymap, error) {
+	km := DefaultKeymap()
+	s := bufio.NewScanner(r)
+	var n int
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) != 2 {
+			return nil, fmt.Errorf("line %d: expected key and action", n)
+		}
+		if why, ok := reservedKeys[f[0]]; ok {
+			return nil, fmt.Errorf("line %d: key '%s' can't be bound, it %s", n, f[0], why)
+		}
+		if !validKeyName(f[0]) {
+			return nil, fmt.Errorf("line %d: unknown key '%s'", n, f[0])
+		}
+		a := KeyAction(f[1])
+		if !validKeyAction(a) {
+			return nil, fmt.Errorf("line %d: unknown action '%s'", n, f[1])
+		}
+		if a == KeyNone {
+			delete(km, f[0])
+			continue
+		}
+		km[f[0]] = a
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return km, nil
+}
+
+// LoadKeymap will read key bindings from a file (see ParseKeymap).
+func LoadKeymap(name string) (Keymap, error) {
+	fd, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ParseKeymap(fd)
+}
+
+// help returns a line for each bound action, listing its keys.
+func (km Keymap) help() []string {
+	keys := make(map[KeyAction][]string)
+	for k, a := range km {
+		keys[a] = append(keys[a], k)
+	}
+	var lines []string
+	for _, ka := range keyActions {
+		k := keys[ka.a]
+		if len(k) == 0 {
+			continue
+		}
+		sort.Strings(k)
+		lines = append(lines, fmt.Sprintf("%-12s %s", strings.Join(k, " "), ka.desc))
+	}
+	return lines
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestParseKeymap(t *testing.T) {
+	km, err := ParseKeymap(strings.NewReader(`
+# run with ctrl
+Ctrl-R run
+r none
+F5 check
+`))
+	if err != nil {
+		t.Fatalf("err = %v; want nil", err)
+	}
+	if km["Ctrl-R"] != KeyRun {
+		t.Errorf("Ctrl-R = %s; want run", km["Ctrl-R"])
+	}
+	if _, ok := km["r"]; ok {
+		t.Error("r is bound; want removed")
+	}
+	if km["F5"] != KeyCheck || km["c"] != KeyCheck {
+		t.Errorf("F5, c = %s, %s; want check", km["F5"], km["c"])
+	}
+
+	for _, s := range []string{"Ctrl-R", "Foo run", "r launch", "Ctrl-C run", "Ctrl-L run", "Ctrl-I run", "Ctrl-M run", "Ctrl-[ run"} {
+		_, err = ParseKeymap(strings.NewReader(s))
+		if err == nil {
+			t.Errorf("%s: err = nil; want error", s)
+		}
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	for _, tc := range []struct {
+		ev   termbox.Event
+		name string
+	}{
+		{termbox.Event{Ch: 'r'}, "r"},
+		{termbox.Event{Key: termbox.KeyArrowUp}, "Up"},
+		{termbox.Event{Key: termbox.KeyTab}, "Tab"},
+		{termbox.Event{Key: termbox.KeyCtrlR}, "Ctrl-R"},
+	} {
+		if n := keyName(tc.ev); n != tc.name {
+			t.Errorf("keyName = %s; want %s", n, tc.name)
+		}
+	}
+}
+
+func TestNextJogStep(t *testing.T) {
+	if s := nextJogStep(0.01, jogStepIncr); s != 0.1 {
+		t.Errorf("step+ = %g; want 0.1", s)
+	}
+	if s := nextJogStep(0.001, jogStepDecr); s != 0.001 {
+		t.Errorf("step- = %g; want 0.001", s)
+	}
+	if s := nextJogStep(0.01, 10); s != 10 {
+		t.Errorf("step = %g; want 10", s)
+	}
+}
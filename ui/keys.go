@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"github.com/mastercactapus/gg/grbl"
+	termbox "github.com/nsf/termbox-go"
+)
+
+var jogKeys = map[KeyAction]byte{
+	KeyJogXPlus:  'X',
+	KeyJogXMinus: 'x',
+	KeyJogYPlus:  'Y',
+	KeyJogYMinus: 'y',
+	KeyJogZPlus:  'Z',
+	KeyJogZMinus: 'z',
+}
+
+var stepKeys = map[KeyAction]float64{
+	KeyStep0001:  0.001,
+	KeyStep001:   0.01,
+	KeyStep01:    0.1,
+	KeyStep1:     1,
+	KeyStep10:    10,
+	KeyStepPlus:  jogStepIncr,
+	KeyStepMinus: jogStepDecr,
+}
+
+// SetKeymap will set the key bindings. It must be called before Start.
+func (j *JobUI) SetKeymap(km Keymap) {
+	j.keymap = km
+}
+
+// onKey handles key events from the UI. Actions for focus and help are handled directly,
+// the rest are sent to the loop.
+func (j *JobUI) onKey(ev termbox.Event) {
+	if j.help && ev.Key == termbox.KeyEsc {
+		j.help = false
+		return
+	}
+	a, ok := j.keymap[keyName(ev)]
+	if !ok {
+		return
+	}
+	switch a {
+	case KeyFocusNext:
+		j.ui.FocusNext()
+	case KeyFocusPrev:
+		j.ui.FocusPrev()
+	case KeyActivate:
+		j.ui.Activate()
+	case KeyHelp:
+		j.help = !j.help
+	default:
+		select {
+		case j.keyCh <- a:
+		default:
+		}
+	}
+}
+
+// handleKeyAction performs a key action, if the matching button would be enabled.
+func (j *JobUI) handleKeyAction(a KeyAction) {
+	idle := j.s.State == grbl.StateIdle
+	if w, ok := jogKeys[a]; ok {
+		if idle || j.s.State == grbl.StateJog {
+			j.keyJog(w)
+		}
+		return
+	}
+	if v, ok := stepKeys[a]; ok {
+		j.jogStep = nextJogStep(j.jogStep, v)
+		return
+	}
+
+	switch a {
+	case KeyCheck:
+		if idle {
+			j.handleAction(actionCheckCode)
+		}
+	case KeyRun:
+		if idle && j.checked {
+			j.handleAction(actionRunJob)
+		}
+	case KeyStop:
+		if j.isRunning() {
+			j.handleAction(actionStopJob)
+		}
+	case KeyHold:
+		j.c.FeedHold()
+	case KeyResume:
+		if j.s.State == grbl.StateHoldComplete {
+			j.c.StartResume()
+		}
+	case KeyHome:
+		if idle || j.s.State == grbl.StateAlarm {
+			j.home()
+		}
+	case KeyUnlock:
+		if j.s.State == grbl.StateAlarm {
+			j.c.Unlock()
+		}
+	case KeyNextError:
+		if len(j.lineErrs) > 0 {
+			j.viewActions = append(j.viewActions, a)
+		}
+	case KeyFind, KeyFindNext, KeyGoToLine, KeyFollow:
+		j.viewActions = append(j.viewActions, a)
+	}
+}
+
+func (j *JobUI) keyHelp() Control {
+	if !j.help {
+		return nil
+	}
+	lines := j.keymap.help()
+	return &Group{
+		Title:  "Keys -- Esc to close",
+		X:      20,
+		Y:      3,
+		Width:  44,
+		Height: len(lines) + 2,
+		Clear:  true,
+		Controls: []Control{
+			&Text{Lines: lines},
+		},
+	}
+}
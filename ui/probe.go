@@ -0,0 +1,253 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/probe"
+)
+
+// touchPlateDistance is the maximum travel, in mm, when searching for the touch plate. It is longer than
+// probe.Config.Distance, as the tool may start well above the plate (e.g. after a tool change).
+const touchPlateDistance = 50
+
+// SetTouchPlate will set the thickness (in mm) of the touch plate used to probe Z zero.
+// It must be called before Start.
+func (j *JobUI) SetTouchPlate(mm float64) {
+	j.touchPlate = mm
+}
+
+func (j *JobUI) performProbeZ() {
+	j.probing = true
+	go func() {
+		err := j.probeZ()
+		if err != nil {
+			log.Println("probe Z:", err)
+		}
+		j.probeDone <- ""
+	}()
+}
+
+type probeRoutine int
+
+const (
+	probeCornerFL probeRoutine = iota
+	probeCornerFR
+	probeCornerBL
+	probeCornerBR
+	probeBore
+	probeBoss
+	probeSurface
+
+	// The height routines probe the top of the stock, then the table past the edge in the
+	// direction of the named axis (e.g. the front edge for Y-). The probe must start above
+	// the stock, within probe.Config.Offset of that edge.
+	probeHeightXMinus
+	probeHeightXPlus
+	probeHeightYMinus
+	probeHeightYPlus
+)
+
+// SetProbeDiameter will set the tip diameter (in mm) of the probe used for edge finding.
+// It must be called before Start.
+func (j *JobUI) SetProbeDiameter(mm float64) {
+	j.probeCfg.Diameter = mm
+}
+
+func (j *JobUI) performProbe(r probeRoutine) {
+	j.probing = true
+	go func() {
+		msg, err := j.runProbe(r)
+		if err != nil {
+			log.Println("probe:", err)
+			msg = "Probe failed"
+		}
+		j.probeDone <- msg
+	}()
+}
+
+// runProbe will run a probing routine and apply the result to the active coordinate system,
+// returning a short description of the result.
+func (j *JobUI) runProbe(r probeRoutine) (string, error) {
+	p := probe.NewProber(j.c, j.probeCfg)
+	var l gcode.Line
+	var size float64
+	var err error
+	var name string
+	switch r {
+	case probeCornerFL:
+		name = "Corner"
+		l, err = p.Corner(-1, -1)
+	case probeCornerFR:
+		name = "Corner"
+		l, err = p.Corner(1, -1)
+	case probeCornerBL:
+		name = "Corner"
+		l, err = p.Corner(-1, 1)
+	case probeCornerBR:
+		name = "Corner"
+		l, err = p.Corner(1, 1)
+	case probeBore:
+		name = "Bore"
+		l, size, err = p.Bore()
+	case probeBoss:
+		name = "Boss"
+		l, size, err = p.Boss()
+	case probeSurface:
+		name = "Top"
+		l, err = p.Surface()
+	case probeHeightXMinus, probeHeightXPlus, probeHeightYMinus, probeHeightYPlus:
+		axis, dir := byte('X'), -1.0
+		switch r {
+		case probeHeightXPlus:
+			dir = 1
+		case probeHeightYMinus:
+			axis = 'Y'
+		case probeHeightYPlus:
+			axis, dir = 'Y', 1
+		}
+		// the stock height is only measured, not applied
+		_, size, err = p.Height(axis, dir)
+		if err != nil {
+			return "", err
+		}
+		msg := fmt.Sprintf("Height %.3f", size)
+		return msg, j.jl.Comment("Probe: " + msg)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = j.setProbedOffset(l)
+	if err != nil {
+		return "", err
+	}
+	msg := name + " " + l.String()
+	if size != 0 {
+		msg = fmt.Sprintf("%s ⌀%.3f", name, size)
+	}
+	err = j.jl.Comment("Probe: " + name + " " + l.String())
+	if err != nil {
+		log.Println("failed to write log:", err)
+	}
+	return msg, nil
+}
+
+// setProbedOffset will set the zero of the active coordinate system to the given machine
+// coordinates (in mm), accounting for any G92 and tool length offset.
+func (j *JobUI) setProbedOffset(l gcode.Line) error {
+	p, err := j.c.Parameters()
+	if err != nil {
+		return err
+	}
+	axes := make(gcode.Line, len(l))
+	for i, w := range l {
+		switch w.Type {
+		case 'X':
+			w.Value -= p.G92[0]
+		case 'Y':
+			w.Value -= p.G92[1]
+		case 'Z':
+			w.Value -= p.G92[2] + p.TLO
+		}
+		axes[i] = w
+	}
+	// probing leaves Grbl in mm
+	return j.c.SetWorkOffset(0, axes)
+}
+
+// retractZ moves Z to the given machine position.
+func (j *JobUI) retractZ(z float64) error {
+	return j.c.ExecLine(gcode.Line{
+		{Type: 'G', Value: 53},
+		{Type: 'G', Value: 0},
+		{Type: 'Z', Value: z},
+	})
+}
+
+// touchZ will find the top of the touch plate, returning the machine Z position where it was touched.
+// The tool is left backed off by the retract distance of the probe configuration.
+func (j *JobUI) touchZ() (float64, error) {
+	pos, err := probe.NewProber(j.c, j.probeCfg).Touch('Z', -1, touchPlateDistance)
+	return pos[2], err
+}
+
+// probeZ will find the top of the touch plate, and set Z zero of the active coordinate system
+// to the surface below it.
+func (j *JobUI) probeZ() error {
+	z, err := j.touchZ()
+	if err != nil {
+		return err
+	}
+
+	err = j.setProbedOffset(gcode.Line{{Type: 'Z', Value: z - j.touchPlate}})
+	if err != nil {
+		return err
+	}
+
+	err = j.jl.Comment(fmt.Sprintf("Probe Z: touched at %.3f with %.3fmm plate", z, j.touchPlate))
+	if err != nil {
+		log.Println("failed to write log:", err)
+	}
+	return nil
+}
+
+// Height map parameters, in mm.
+const (
+	// heightMapStep is the maximum distance between probed points.
+	heightMapStep = 10
+
+	// levelSegment is the maximum length of a leveled move.
+	levelSegment = 2
+)
+
+// SetHeightMap will level the job using a previously measured height map.
+// It must be called before Start.
+func (j *JobUI) SetHeightMap(h *gcode.HeightMap) {
+	j.heightMap = h
+	j.level = true
+	j.probeText = heightMapText(h)
+}
+
+func heightMapText(h *gcode.HeightMap) string {
+	if len(h.Z) == 0 {
+		return ""
+	}
+	min, max := h.Z[0], h.Z[0]
+	for _, z := range h.Z {
+		min = math.Min(min, z)
+		max = math.Max(max, z)
+	}
+	return fmt.Sprintf("Map %dx%d Δ%.3f", h.Cols, h.Rows, max-min)
+}
+
+func (j *JobUI) performHeightMap() {
+	if len(j.s.WCO) < 3 {
+		log.Println("height map: work coordinate offset unknown")
+		return
+	}
+	wco := [3]float64{j.s.WCO[0], j.s.WCO[1], j.s.WCO[2]}
+	min, max, err := gcode.Bounds(j.g)
+	if err != nil {
+		log.Println("height map:", err)
+		return
+	}
+	h := gcode.NewHeightMap([2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}, heightMapStep)
+
+	j.probing = true
+	go func() {
+		err := probe.NewProber(j.c, j.probeCfg).HeightMap(h, wco)
+		if err != nil {
+			log.Println("height map:", err)
+			j.probeDone <- "Map failed"
+			return
+		}
+		err = j.jl.HeightMap(h)
+		if err != nil {
+			log.Println("failed to write log:", err)
+		}
+		j.recvHeightMap <- h
+	}()
+}
@@ -25,6 +25,9 @@ func (j *JobUI) handleShuttleEvent(e shuttlexpress.Event) {
 		case 3:
 			j.shuttleAxis = 'Z'
 		}
+		if j.jog != nil && !j.jog.key && j.jog.axis != j.shuttleAxis {
+			j.stopJog()
+		}
 	case shuttlexpress.EventTypeConnection:
 		switch e.Value {
 		case shuttlexpress.ConnectionFailed:
@@ -41,10 +44,38 @@ func (j *JobUI) handleShuttleEvent(e shuttlexpress.Event) {
 		j.shuttleMove(e.Value)
 	case shuttlexpress.EventTypeRing:
 		j.shuttleRing = e.Value
-		if e.Value == 0 {
-			j.c.JogCancel()
+		if j.jobActive() {
+			j.shuttleFeedOverride(e.Value)
+			return
 		}
+		j.shuttleJog(e.Value)
+	}
+}
+
+// jobActive returns true if a job is running or paused.
+func (j *JobUI) jobActive() bool {
+	switch j.s.State {
+	case grbl.StateRun, grbl.StateHoldActive, grbl.StateHoldComplete:
+		return true
+	}
+	return false
+}
+
+// shuttleFeedOverride will set the feed override from the ring position, 10% per step.
+func (j *JobUI) shuttleFeedOverride(ring int) {
+	if ring == 0 {
+		j.c.FeedOverrideReset()
+		j.s.FieldOverrides.Feed = 100
+		return
+	}
+
+	current := j.s.FieldOverrides.Feed
+	if current == 0 {
+		current = 100
 	}
+	target := 100 + ring*10
+	j.c.FeedOverride(target - current)
+	j.s.FieldOverrides.Feed = target
 }
 func (j *JobUI) shuttleMove(val int) {
 	if !j.shuttleConnected {
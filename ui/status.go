@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/mastercactapus/gg/grbl"
 	termbox "github.com/nsf/termbox-go"
@@ -45,4 +46,16 @@ func (s *Status) Draw(r Renderer) {
 	if s.Settings != nil {
 		printCoords(s.X, s.Y+8, 10, "Max", []float64{s.MaxTravel.X.Millimeters(), s.MaxTravel.Y.Millimeters(), s.MaxTravel.Z.Millimeters()})
 	}
+
+	b := s.Buffers
+	used := func(n int) string {
+		if s.Settings == nil || !s.StatusReport.BufferData {
+			// not reported
+			return "-"
+		}
+		return strconv.Itoa(n)
+	}
+	putRunes(r, s.X, s.Y+12, []rune(fmt.Sprintf("Planner %4s/%-4d", used(b.PlannerUsed), b.PlannerBlocks)))
+	putRunes(r, s.X, s.Y+13, []rune(fmt.Sprintf("Serial  %4s/%-4d", used(b.RXUsed), b.RXBytes)))
+	putRunes(r, s.X, s.Y+14, []rune(fmt.Sprintf("Sent    %4d +%-4d", b.Pending, b.Queued)))
 }
@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl"
+)
+
+type toolChangeAction int
+
+const (
+	toolChangeContinue toolChangeAction = iota
+	toolChangeProbe
+	toolChangeStop
+)
+
+// toolChange is a tool change waiting on the operator.
+type toolChange struct {
+	tool int
+	line int
+	msg  string
+}
+
+// SetToolChangeClearance will set the distance (in mm) below machine Z zero to retract to
+// for tool changes. It must be called before Start.
+func (j *JobUI) SetToolChangeClearance(mm float64) {
+	j.toolChangeZ = -mm
+}
+
+// runJob will stream prog to Grbl, pausing before each tool change. Lines without a line number
+// (e.g. the resume preamble or leveled segments) are shown as part of the line before, starting with ln.
+func (j *JobUI) runJob(prog []gcode.Line, ln int) {
+	var pre []gcode.Line
+	var failed bool
+	start := 0
+	for _, end := range append(gcode.ToolChanges(prog), len(prog)) {
+		ln, failed = j.runLines(append(pre, prog[start:end]...), ln)
+		if end == len(prog) {
+			return
+		}
+		if failed {
+			log.Println("tool change: job stopped due to errors")
+			return
+		}
+
+		var ok bool
+		pre, ok = j.changeTool(prog, end)
+		if !ok {
+			return
+		}
+		start = end
+	}
+}
+
+// runLines will stream lines to Grbl, reporting progress and logging each response. The last line number
+// is returned, along with true if any line failed.
+func (j *JobUI) runLines(lines []gcode.Line, ln int) (int, bool) {
+	if len(lines) == 0 {
+		return ln, false
+	}
+	var failed bool
+	for stat := range j.c.RunGCode(lines) {
+		l := lines[stat.Line]
+		if len(l) > 0 && l[0].Type == 'N' {
+			ln = int(l[0].Value)
+		}
+		j.logResult(l, stat.Err)
+		if stat.Err != nil {
+			failed = true
+		}
+		j.jobStatus <- gcodeStatus{
+			line: ln,
+			err:  stat.Err,
+		}
+	}
+	return ln, failed
+}
+
+// changeTool will stop the spindle and retract for the tool change at prog[i], then wait for the
+// operator. The lines to continue the program are returned, or false if the job was stopped.
+func (j *JobUI) changeTool(prog []gcode.Line, i int) ([]gcode.Line, bool) {
+	states, err := gcode.Interpret(prog[:i+1])
+	if err != nil {
+		log.Println("tool change:", err)
+		return nil, false
+	}
+	pre, err := gcode.ToolChangeResume(prog, i)
+	if err != nil {
+		log.Println("tool change:", err)
+		return nil, false
+	}
+	tc := &toolChange{tool: states[i].Tool, line: i + 1}
+	if n := prog[i]; len(n) > 0 && n[0].Type == 'N' {
+		tc.line = int(n[0].Value)
+	}
+
+	// the dwell waits for all motion to complete
+	for _, l := range []gcode.Line{
+		{{Type: 'G', Value: 4}, {Type: 'P', Value: 0}},
+		{{Type: 'M', Value: 5}, {Type: 'M', Value: 9}},
+		{{Type: 'G', Value: 53}, {Type: 'G', Value: 0}, {Type: 'Z', Value: j.toolChangeZ}},
+	} {
+		err = j.c.ExecLine(l)
+		if err != nil {
+			log.Println("tool change:", err)
+			return nil, false
+		}
+	}
+
+	j.logComment(fmt.Sprintf("Tool change: T%d at line %d", tc.tool, tc.line))
+	for {
+		j.toolChangeCh <- tc
+		switch <-j.toolChangeResp {
+		case toolChangeStop:
+			j.logComment("Tool change: job stopped")
+			return nil, false
+		case toolChangeContinue:
+			return pre, true
+		case toolChangeProbe:
+			tlo, err := j.probeToolLength()
+			if err != nil {
+				log.Println("probe tool length:", err)
+				tc = &toolChange{tool: tc.tool, line: tc.line, msg: "Probe failed"}
+				continue
+			}
+			tc = &toolChange{tool: tc.tool, line: tc.line, msg: fmt.Sprintf("TLO %.3f", tlo)}
+		}
+	}
+}
+
+// probeToolLength will touch the new tool off on the touch plate, and set the tool length offset so
+// that the work zero is unchanged. The new offset is returned.
+func (j *JobUI) probeToolLength() (float64, error) {
+	p, err := j.c.Parameters()
+	if err != nil {
+		return 0, err
+	}
+	if p.ActiveWCS == 0 {
+		return 0, errors.New("unknown active coordinate system")
+	}
+	z, err := j.touchZ()
+	if err != nil {
+		return 0, err
+	}
+
+	tlo := z - j.touchPlate - p.Offset(p.ActiveWCS)[2] - p.G92[2]
+	err = j.c.ExecLine(gcode.Line{{Type: 'G', Value: 21}, {Type: 'G', Value: 43.1}, {Type: 'Z', Value: tlo}})
+	if err != nil {
+		return 0, err
+	}
+	j.logComment(fmt.Sprintf("Tool length offset: touched at %.3f, set to %.3f", z, tlo))
+
+	return tlo, j.retractZ(j.toolChangeZ)
+}
+
+func (j *JobUI) logComment(s string) {
+	err := j.jl.Comment(s)
+	if err != nil {
+		log.Println("failed to write log:", err)
+	}
+}
+
+func (j *JobUI) toolChangeStatus() Control {
+	idle := j.s.State == grbl.StateIdle
+	return &Group{
+		X: 1, Y: 3, Height: 3,
+		Width: -1,
+		Title: fmt.Sprintf("Tool change: T%d at line %d", j.toolChange.tool, j.toolChange.line),
+		Clear: true,
+		Controls: []Control{
+			&Button{X: 1, Text: "Probe Length", Enabled: idle,
+				OnClickFunc: func(int, int) { j.toolChangeActionCh <- toolChangeProbe },
+			},
+			&Button{X: 18, Text: "Continue", Enabled: idle,
+				OnClickFunc: func(int, int) { j.toolChangeActionCh <- toolChangeContinue },
+			},
+			&Button{X: 31, Text: "Stop", Enabled: true,
+				OnClickFunc: func(int, int) { j.toolChangeActionCh <- toolChangeStop },
+			},
+			&Text{X: 40, Lines: []string{j.toolChange.msg}},
+		},
+	}
+}
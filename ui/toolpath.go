@@ -0,0 +1,384 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mastercactapus/gg/gcode"
+	termbox "github.com/nsf/termbox-go"
+)
+
+const (
+	// toolpathZoomStep is the change in zoom for each scroll event.
+	toolpathZoomStep = 1.25
+
+	// toolpathArcTolerance is the tolerance used to draw arcs, in mm.
+	toolpathArcTolerance = 0.01
+)
+
+// Toolpath colors
+const (
+	toolpathPendingFG = termbox.ColorDefault
+	toolpathRapidFG   = termbox.ColorBlue
+	toolpathDoneFG    = termbox.ColorGreen
+	toolpathToolFG    = termbox.ColorRed | termbox.AttrBold
+)
+
+type toolpathSegment struct {
+	// line is the line number of the move.
+	line  int
+	rapid bool
+	pts   [][3]float64
+}
+
+// Toolpath draws an XY (and optionally XZ) projection of a program using braille characters.
+//
+// Scrolling will zoom in and out around the mouse, and clicking will center the view there.
+type Toolpath struct {
+	X, Y          int
+	Width, Height int
+
+	// Active is the line number being run. Moves before it are shown as complete.
+	Active int
+
+	// Pos is the current tool position, in work coordinates, or nil if unknown.
+	Pos []float64
+
+	// Side will split the view, showing the XZ projection below XY.
+	Side bool
+
+	segs     []toolpathSegment
+	min, max [3]float64
+
+	// zoom is relative to fitting the whole program, center is the XY position in the middle of the view.
+	zoom   float64
+	center [2]float64
+
+	// size of the XY view in cells from the last Draw
+	w, h int
+}
+
+// NewToolpath will create a new Toolpath for the given program. Lines should be numbered
+// (N words) to be highlighted as they complete.
+//
+// If the program can't be interpreted, the Toolpath up to the failed line is returned along with the error.
+func NewToolpath(lines []gcode.Line) (*Toolpath, error) {
+	t := &Toolpath{zoom: 1}
+	defer t.Fit()
+
+	// only show the lines that can be interpreted
+	var err error
+	in := gcode.NewInterpreter()
+	for i, l := range lines {
+		if e := in.Exec(l); e != nil {
+			err = fmt.Errorf("line %d: %v", i+1, e)
+			lines = lines[:i]
+			break
+		}
+	}
+	ext, _ := gcode.Extents(lines)
+
+	first := true
+	in = gcode.NewInterpreter()
+	known := false
+	for i, l := range lines {
+		start := in.State().Position
+		in.Exec(l)
+		e := ext[i]
+		if e == nil {
+			continue
+		}
+		if e.Machine || hasNaN(e.Min) {
+			// the start of the next move is unknown
+			known = false
+			continue
+		}
+		if !known {
+			known = true
+			continue
+		}
+
+		s := toolpathSegment{
+			line:  i + 1,
+			rapid: in.State().Motion == gcode.MotionRapid,
+			pts:   [][3]float64{start, in.State().Position},
+		}
+		if len(l) > 0 && l[0].Type == 'N' {
+			s.line = int(l[0].Value)
+		}
+		if a := in.Arc(); a != nil {
+			s.pts = append([][3]float64{start}, a.Points(toolpathArcTolerance)...)
+		}
+		t.segs = append(t.segs, s)
+		for _, p := range s.pts {
+			for j := range p {
+				if first {
+					t.min[j], t.max[j] = p[j], p[j]
+					continue
+				}
+				t.min[j] = math.Min(t.min[j], p[j])
+				t.max[j] = math.Max(t.max[j], p[j])
+			}
+			first = false
+		}
+	}
+	return t, err
+}
+
+func hasNaN(p [3]float64) bool {
+	return math.IsNaN(p[0]) || math.IsNaN(p[1]) || math.IsNaN(p[2])
+}
+
+// Fit will reset the view to show the whole program.
+func (t *Toolpath) Fit() {
+	t.zoom = 1
+	t.center = [2]float64{(t.min[0] + t.max[0]) / 2, (t.min[1] + t.max[1]) / 2}
+}
+
+// toolpathView maps positions (u, v) to dots of a brailleCanvas, with v up.
+type toolpathView struct {
+	su, sv float64
+	cu, cv float64
+	w, h   int
+}
+
+func (v toolpathView) dot(pu, pv float64) (float64, float64) {
+	return float64(v.w)/2 + (pu-v.cu)*v.su, float64(v.h)/2 - (pv-v.cv)*v.sv
+}
+func (v toolpathView) pos(x, y float64) (float64, float64) {
+	return v.cu + (x-float64(v.w)/2)/v.su, v.cv - (y-float64(v.h)/2)/v.sv
+}
+
+// fitScale returns the scale (dots per mm) to fit size (in mm) within w by h dots.
+func fitScale(w, h int, su, sv float64) float64 {
+	// leave a margin of one dot on each side
+	s := math.Inf(1)
+	if su > 0 {
+		s = float64(w-2) / su
+	}
+	if sv > 0 {
+		s = math.Min(s, float64(h-2)/sv)
+	}
+	if math.IsInf(s, 1) || s <= 0 {
+		return 1
+	}
+	return s
+}
+
+// views returns the XY and XZ views for a canvas of w by h cells, with the XZ view using the bottom
+// sideH cells, below a divider.
+func (t *Toolpath) views(w, h, sideH int) (xy, xz toolpathView) {
+	size := [3]float64{t.max[0] - t.min[0], t.max[1] - t.min[1], t.max[2] - t.min[2]}
+	xy = toolpathView{w: w * 2, h: (h - sideH) * 4, cu: t.center[0], cv: t.center[1]}
+	xy.su = fitScale(xy.w, xy.h, size[0], size[1]) * t.zoom
+	xy.sv = xy.su
+
+	// X lines up with the XY view, Z is always fit
+	xz = toolpathView{w: w * 2, h: (sideH - 1) * 4, cu: t.center[0], cv: (t.min[2] + t.max[2]) / 2}
+	xz.su = xy.su
+	xz.sv = fitScale(xz.w, xz.h, 0, size[2])
+	return xy, xz
+}
+
+func (t *Toolpath) sideHeight(h int) int {
+	if !t.Side {
+		return 0
+	}
+	if h < 6 {
+		return 0
+	}
+	return h / 3
+}
+
+func (t *Toolpath) Draw(r Renderer) {
+	sw, sh := r.Size()
+	x, y, w, h := StandardSize(t.X, t.Y, t.Width, t.Height, sw, sh)
+	if w < 1 || h < 1 {
+		return
+	}
+	sideH := t.sideHeight(h)
+	t.w, t.h = w, h-sideH
+	xy, xz := t.views(w, h, sideH)
+
+	c := newBrailleCanvas(w, h)
+	project := func(v toolpathView, top int, p [3]float64, side bool) (float64, float64) {
+		var dx, dy float64
+		if side {
+			dx, dy = v.dot(p[0], p[2])
+		} else {
+			dx, dy = v.dot(p[0], p[1])
+		}
+		return dx, dy + float64(top*4)
+	}
+	draw := func(v toolpathView, top int, side bool) {
+		// complete moves are drawn last, so they take precedence over pending ones in the same cell
+		for _, done := range []bool{false, true} {
+			for _, s := range t.segs {
+				if (s.line < t.Active) != done {
+					continue
+				}
+				fg := toolpathPendingFG
+				switch {
+				case done:
+					fg = toolpathDoneFG
+				case s.rapid:
+					fg = toolpathRapidFG
+				}
+				x0, y0 := project(v, top, s.pts[0], side)
+				for _, p := range s.pts[1:] {
+					x1, y1 := project(v, top, p, side)
+					c.line(x0, y0, x1, y1, top*4, (top*4)+v.h, fg)
+					x0, y0 = x1, y1
+				}
+			}
+		}
+	}
+	draw(xy, 0, false)
+	if sideH > 0 {
+		draw(xz, h-sideH+1, true)
+	}
+	c.draw(r, x, y)
+
+	if sideH > 0 {
+		for col := 0; col < w; col++ {
+			r.SetCell(x+col, y+h-sideH, '┄', termbox.ColorCyan, 0)
+		}
+		putRunesA(r, x, y+h-sideH, []rune("XZ"), termbox.ColorCyan, 0)
+	}
+
+	if len(t.Pos) < 3 {
+		return
+	}
+	pos := [3]float64{t.Pos[0], t.Pos[1], t.Pos[2]}
+	mark := func(v toolpathView, top int, side bool) {
+		dx, dy := project(v, top, pos, side)
+		cx, cy := int(math.Floor(dx/2)), int(math.Floor(dy/4))
+		if cx < 0 || cx >= w || cy < top || cy >= top+v.h/4 {
+			return
+		}
+		r.SetCell(x+cx, y+cy, '✛', toolpathToolFG, 0)
+	}
+	mark(xy, 0, false)
+	if sideH > 0 {
+		mark(xz, h-sideH+1, true)
+	}
+}
+
+// OnScroll will zoom in (scroll up) or out around the given cell of the XY view.
+func (t *Toolpath) OnScroll(x, y int, e EventType) {
+	if t.w < 1 || t.h < 1 || y >= t.h {
+		return
+	}
+	xy, _ := t.views(t.w, t.h, 0)
+	px, py := xy.pos(float64(x*2+1), float64(y*4+2))
+	f := toolpathZoomStep
+	if e == EventTypeScrollDown {
+		f = 1 / f
+	}
+	if t.zoom*f < 1/toolpathZoomStep/toolpathZoomStep {
+		return
+	}
+	t.zoom *= f
+	// keep the position under the mouse in place
+	t.center[0] = px + (t.center[0]-px)/f
+	t.center[1] = py + (t.center[1]-py)/f
+}
+
+// OnClick will center the view on the given cell of the XY view.
+func (t *Toolpath) OnClick(x, y int) {
+	if t.w < 1 || t.h < 1 || y >= t.h {
+		return
+	}
+	xy, _ := t.views(t.w, t.h, 0)
+	t.center[0], t.center[1] = xy.pos(float64(x*2+1), float64(y*4+2))
+}
+
+// brailleCanvas is a grid of cells, each holding 2x4 dots.
+type brailleCanvas struct {
+	w, h int
+	dots []uint8
+	fg   []termbox.Attribute
+}
+
+// brailleBits are the bits of each dot in a braille character, by [y][x].
+var brailleBits = [4][2]uint8{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+func newBrailleCanvas(w, h int) *brailleCanvas {
+	return &brailleCanvas{
+		w: w, h: h,
+		dots: make([]uint8, w*h),
+		fg:   make([]termbox.Attribute, w*h),
+	}
+}
+
+func (c *brailleCanvas) set(x, y int, fg termbox.Attribute) {
+	if x < 0 || y < 0 || x >= c.w*2 || y >= c.h*4 {
+		return
+	}
+	i := (y/4)*c.w + x/2
+	c.dots[i] |= brailleBits[y%4][x%2]
+	c.fg[i] = fg
+}
+
+// line will draw a line between two dots, clipped to the rows from top to bottom (exclusive).
+func (c *brailleCanvas) line(x0, y0, x1, y1 float64, top, bottom int, fg termbox.Attribute) {
+	x0, y0, x1, y1, ok := clipLine(x0, y0, x1, y1, 0, float64(top), float64(c.w*2)-1e-9, float64(bottom)-1e-9)
+	if !ok {
+		return
+	}
+	n := int(math.Ceil(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))))
+	for i := 0; i <= n; i++ {
+		t := 0.0
+		if n > 0 {
+			t = float64(i) / float64(n)
+		}
+		c.set(int(math.Floor(x0+(x1-x0)*t)), int(math.Floor(y0+(y1-y0)*t)), fg)
+	}
+}
+
+func (c *brailleCanvas) draw(r CellSetter, x, y int) {
+	for i, d := range c.dots {
+		ch := ' '
+		if d != 0 {
+			ch = rune(0x2800 + int(d))
+		}
+		r.SetCell(x+i%c.w, y+i/c.w, ch, c.fg[i], 0)
+	}
+}
+
+// clipLine will clip a line to a rectangle using the Liang-Barsky algorithm. ok is false if
+// the line is entirely outside of it.
+func clipLine(x0, y0, x1, y1, minX, minY, maxX, maxY float64) (float64, float64, float64, float64, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := x1-x0, y1-y0
+	edges := [4][2]float64{
+		{-dx, x0 - minX},
+		{dx, maxX - x0},
+		{-dy, y0 - minY},
+		{dy, maxY - y0},
+	}
+	for _, e := range edges {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+	}
+	if t0 > t1 {
+		return 0, 0, 0, 0, false
+	}
+	return x0 + t0*dx, y0 + t0*dy, x0 + t1*dx, y0 + t1*dy, true
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mastercactapus/gg/gcode"
+	termbox "github.com/nsf/termbox-go"
+)
+
+type testScreen struct {
+	w, h  int
+	cells map[[2]int]rune
+}
+
+func (s *testScreen) Size() (int, int) { return s.w, s.h }
+func (s *testScreen) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	s.cells[[2]int{x, y}] = ch
+}
+
+func TestBrailleCanvas(t *testing.T) {
+	c := newBrailleCanvas(2, 1)
+	c.line(0, 0, 3, 3, 0, 4, 0)
+
+	s := &testScreen{w: 2, h: 1, cells: make(map[[2]int]rune)}
+	c.draw(s, 0, 0)
+	if ch := s.cells[[2]int{0, 0}]; ch != '⠑' {
+		t.Errorf("cell 0 = %c; want ⠑", ch)
+	}
+	if ch := s.cells[[2]int{1, 0}]; ch != '⢄' {
+		t.Errorf("cell 1 = %c; want ⢄", ch)
+	}
+}
+
+func TestToolpath(t *testing.T) {
+	blocks, err := gcode.Parse(bytes.NewBufferString(`
+G21 G90
+G0 X0 Y0 Z5
+G1 Z-1 F100
+G1 X10
+G2 X20 Y0 I5 J0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp, err := NewToolpath(gcode.Lines(blocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tp.segs) != 3 {
+		t.Fatalf("segments = %d; want 3", len(tp.segs))
+	}
+	if tp.min != [3]float64{0, 0, -1} || tp.max[0] != 20 || tp.max[2] != 5 {
+		t.Errorf("bounds = %v, %v; want [0 0 -1], [20 5 5]", tp.min, tp.max)
+	}
+
+	r := newBoundedRenderer(&testScreen{w: 20, h: 10, cells: make(map[[2]int]rune)}, Rect{Right: 20, Bottom: 10})
+	tp.Draw(r)
+	tp.OnScroll(10, 5, EventTypeScrollUp)
+	if tp.zoom != toolpathZoomStep {
+		t.Errorf("zoom = %f; want %f", tp.zoom, toolpathZoomStep)
+	}
+	tp.Fit()
+	if tp.center != [2]float64{10, 2.5} {
+		t.Errorf("center = %v; want [10 2.5]", tp.center)
+	}
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/mastercactapus/gg/gcode"
+	"github.com/mastercactapus/gg/grbl"
+)
+
+// lineNumber returns the line number of prog[i], or of the closest line before it with one.
+func lineNumber(prog []gcode.Line, i int) int {
+	for ; i >= 0; i-- {
+		if l := prog[i]; len(l) > 0 && l[0].Type == 'N' {
+			return int(l[0].Value)
+		}
+	}
+	return 0
+}
+
+// checkTravel will check that prog stays within the travel of the machine from the current
+// work zero. Lines that would exceed it are marked, and false is returned.
+func (j *JobUI) checkTravel(prog []gcode.Line) bool {
+	j.travelErr = ""
+	if _, _, ok := j.settings.TravelLimits(); !ok {
+		log.Println("travel check skipped: homing is disabled or max travel is unknown")
+		return true
+	}
+	if len(j.s.WCO) < 3 {
+		log.Println("travel check skipped: work coordinate offset is unknown")
+		return true
+	}
+
+	errs, err := grbl.CheckTravel(j.settings, [3]float64{j.s.WCO[0], j.s.WCO[1], j.s.WCO[2]}, prog)
+	if err != nil {
+		j.travelErr = err.Error()
+		log.Println("travel check:", err)
+		return false
+	}
+	if len(errs) == 0 {
+		return true
+	}
+
+	for i, e := range errs {
+		// report program line numbers, rather than the position in prog
+		ln := lineNumber(prog, e.Line)
+		errs[i].Line = ln - 1
+		if _, ok := j.lineErrs[ln]; !ok {
+			j.lineErrs[ln] = errs[i]
+		}
+		log.Println("travel check:", errs[i])
+	}
+	j.travelErr = errs[0].Error()
+	if len(errs) > 1 {
+		j.travelErr += fmt.Sprintf(" (and %d more)", len(errs)-1)
+	}
+	return false
+}
@@ -16,6 +16,14 @@ type UI struct {
 	eventCh  chan termbox.Event
 
 	rendered []renderedControl
+
+	// OnKey, if set, is called for key events not used by a control.
+	OnKey func(ev termbox.Event)
+
+	// focus is the position of the focused control, starting at 1, or 0 for none.
+	focus   int
+	focused Focusable
+	nFocus  int
 }
 type renderedControl struct {
 	r Rect
@@ -63,16 +71,26 @@ func (ui *UI) MainLoop() {
 		case ev := <-ui.eventCh:
 			switch ev.Type {
 			case termbox.EventMouse:
-				if ev.Key != termbox.MouseLeft {
+				switch ev.Key {
+				case termbox.MouseLeft:
+					ui.processClick(ev.MouseX, ev.MouseY)
+				case termbox.MouseWheelUp:
+					ui.processScroll(ev.MouseX, ev.MouseY, EventTypeScrollUp)
+				case termbox.MouseWheelDown:
+					ui.processScroll(ev.MouseX, ev.MouseY, EventTypeScrollDown)
+				default:
 					continue
 				}
-				ui.processClick(ev.MouseX, ev.MouseY)
 			case termbox.EventKey:
 				switch ev.Key {
 				case termbox.KeyCtrlC:
 					return
 				case termbox.KeyCtrlL:
 					termbox.Clear(0, 0)
+				default:
+					if !ui.processKey(ev) && ui.OnKey != nil {
+						ui.OnKey(ev)
+					}
 				}
 
 			case termbox.EventResize:
@@ -95,6 +113,74 @@ func (ui *UI) processClick(x, y int) {
 		cc.OnClick(c.r.Translate(x, y))
 	}
 }
+func (ui *UI) processScroll(x, y int, e EventType) {
+	for _, c := range ui.rendered {
+		sc, ok := c.c.(Scrollable)
+		if !ok {
+			continue
+		}
+		if !c.r.Contains(x, y) {
+			continue
+		}
+		sx, sy := c.r.Translate(x, y)
+		sc.OnScroll(sx, sy, e)
+	}
+}
+func (ui *UI) processKey(ev termbox.Event) bool {
+	for _, c := range ui.rendered {
+		tc, ok := c.c.(Typeable)
+		if ok && tc.OnKey(ev) {
+			return true
+		}
+	}
+	return false
+}
+
+// FocusNext will focus the next enabled control, in the order they are rendered.
+func (ui *UI) FocusNext() {
+	if ui.nFocus == 0 {
+		return
+	}
+	ui.focus = ui.focus%ui.nFocus + 1
+}
+
+// FocusPrev will focus the previous enabled control.
+func (ui *UI) FocusPrev() {
+	if ui.nFocus == 0 {
+		return
+	}
+	ui.focus--
+	if ui.focus < 1 {
+		ui.focus = ui.nFocus
+	}
+}
+
+// Activate will press the focused control.
+func (ui *UI) Activate() {
+	if ui.focused == nil {
+		return
+	}
+	ui.focused.OnClick(0, 0)
+}
+
+// focusables returns the enabled Focusable controls in cs, including those in groups.
+func focusables(cs []Control) []Focusable {
+	var res []Focusable
+	for _, c := range cs {
+		switch c := c.(type) {
+		case *Group:
+			if c != nil {
+				res = append(res, focusables(c.Controls)...)
+			}
+		case Focusable:
+			if c.CanFocus() {
+				res = append(res, c)
+			}
+		}
+	}
+	return res
+}
+
 func (ui *UI) Lock() {
 	ui.mx.Lock()
 }
@@ -118,8 +204,23 @@ func (ui *UI) renderControls() {
 	bounds := Rect{Left: 0, Top: 0, Right: sw, Bottom: sh}
 	b := newBoundedRenderer(ui, bounds)
 	r := ui.r()
+
+	f := focusables(r)
+	ui.nFocus = len(f)
+	if ui.focus > ui.nFocus {
+		ui.focus = ui.nFocus
+	}
+	ui.focused = nil
+	if ui.focus > 0 {
+		ui.focused = f[ui.focus-1]
+		ui.focused.SetFocus(true)
+	}
+
 	ui.rendered = ui.rendered[:0]
 	for _, c := range r {
+		if c == nil {
+			continue
+		}
 		ui.rendered = append(ui.rendered, renderedControl{
 			c: c,
 			r: b.RenderChild(bounds, c),